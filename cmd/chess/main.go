@@ -6,7 +6,10 @@ import (
 	"os"
 	"os/exec"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Waleed-Ahmad-dev/Chess-app/internal/game"
 	"github.com/Waleed-Ahmad-dev/Chess-app/internal/server"
@@ -22,6 +25,24 @@ func main() {
 		return
 	}
 
+	// Count move generator nodes: chess perft <depth> [fen]
+	if len(os.Args) > 2 && os.Args[1] == "perft" {
+		if err := runPerft(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Run an EPD test suite: chess epd <file> [depth]
+	if len(os.Args) > 2 && os.Args[1] == "epd" {
+		if err := runEPD(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Check for sound disable flag
 	soundEnabled := true
 	for _, arg := range os.Args[1:] {
@@ -48,30 +69,26 @@ func main() {
 		// Draw board from the perspective of the current player
 		gameInstance.Board.Draw(gameInstance.Turn)
 
-		// 1. Generate Legal Moves for the current turn
-		legalMoves := gameInstance.GenerateLegalMoves()
-
-		// 2. Check Game Over Conditions
-		if len(legalMoves) == 0 {
-			if gameInstance.Board.InCheck(gameInstance.Turn) {
-				fmt.Printf("\nCheckmate! %s wins!\n", getOpponentColor(gameInstance.Turn))
-				if soundEnabled {
-					sound.PlaySound(sound.SoundCheckmate)
-				}
-			} else {
-				fmt.Println("\nStalemate! The game is a draw.")
+		// 1. Check Game Over Conditions
+		if outcome := gameInstance.Outcome(); outcome.IsOver() {
+			fmt.Printf("\n%s! (%s)\n", outcome, outcome.Result)
+			if soundEnabled && outcome.Termination == game.TerminationCheckmate {
+				sound.PlaySound(sound.SoundCheckmate)
 			}
 			break
 		}
 
-		// 3. Print Status
-		if gameInstance.Board.InCheck(gameInstance.Turn) {
+		// 2. Print Status
+		if gameInstance.InCheck() {
 			fmt.Println("\n⚠️  CHECK! ⚠️")
 		}
 		fmt.Printf("\n%s's turn to move.\n", gameInstance.Turn)
-		fmt.Print("Enter move (e.g., 'e2e4'): ")
+		if reason := gameInstance.ClaimableDraw(); reason != game.TerminationNone {
+			fmt.Printf("You may claim a draw by %s: type 'draw', or play on.\n", reason)
+		}
+		fmt.Print("Enter move (e.g., 'e2e4' or 'Nf3'): ")
 
-		// 4. Read Input
+		// 3. Read Input
 		input, _ := reader.ReadString('\n')
 		input = strings.TrimSpace(input)
 
@@ -80,8 +97,28 @@ func main() {
 			break
 		}
 
-		// 5. Parse & Validate Move
-		move, err := game.ParseMove(input, legalMoves)
+		if input == "fen" {
+			fmt.Printf("%s\nPress Enter to continue...", gameInstance.FEN())
+			reader.ReadString('\n')
+			continue
+		}
+
+		if input == "resign" {
+			gameInstance.Resign(gameInstance.Turn)
+			fmt.Printf("\n%s! (%s)\n", gameInstance.Outcome(), gameInstance.Outcome().Result)
+			break
+		}
+
+		if input == "draw" {
+			if err := gameInstance.ClaimDraw(); err != nil {
+				fmt.Printf("Error: %v\nPress Enter to continue...", err)
+				reader.ReadString('\n')
+			}
+			continue
+		}
+
+		// 4. Parse & Validate Move
+		move, err := gameInstance.ParseMoveInput(input)
 		if err != nil {
 			fmt.Printf("Error: %v\nPress Enter to try again...", err)
 			if soundEnabled {
@@ -91,10 +128,10 @@ func main() {
 			continue
 		}
 
-		// 6. Execute Move
+		// 5. Execute Move
 		result := gameInstance.MakeMove(move)
 
-		// 7. Play appropriate sound
+		// 6. Play appropriate sound
 		if soundEnabled {
 			playMoveSound(result)
 		}
@@ -114,13 +151,6 @@ func clearScreen() {
 	}
 }
 
-func getOpponentColor(c game.Color) string {
-	if c == game.White {
-		return "Black"
-	}
-	return "White"
-}
-
 // playMoveSound plays the appropriate sound for a move
 func playMoveSound(result game.MoveResult) {
 	if result.WasCheckmate {
@@ -151,3 +181,86 @@ func playMoveSound(result game.MoveResult) {
 	// Default move sound
 	sound.PlaySound(sound.SoundMove)
 }
+
+// runPerft prints the divide counts for each move and the perft total
+func runPerft(args []string) error {
+	depth, err := strconv.Atoi(args[0])
+	if err != nil || depth < 1 {
+		return fmt.Errorf("invalid depth %q", args[0])
+	}
+
+	g := game.NewGame()
+	if len(args) > 1 {
+		if err := g.LoadFEN(strings.Join(args[1:], " ")); err != nil {
+			return err
+		}
+	}
+
+	start := time.Now()
+	counts := g.Divide(depth)
+	elapsed := time.Since(start)
+
+	moves := make([]string, 0, len(counts))
+	var total uint64
+	for move, n := range counts {
+		moves = append(moves, move)
+		total += n
+	}
+	sort.Strings(moves)
+	for _, move := range moves {
+		fmt.Printf("%s: %d\n", move, counts[move])
+	}
+
+	fmt.Printf("\nNodes: %d\nTime: %v (%.0f nodes/s)\n", total, elapsed.Round(time.Millisecond), float64(total)/elapsed.Seconds())
+	return nil
+}
+
+// runEPD scores the engine against the bm/am answers of an EPD file
+func runEPD(args []string) error {
+	depth := game.MaxDepth
+	if len(args) > 1 {
+		d, err := strconv.Atoi(args[1])
+		if err != nil || d < 1 {
+			return fmt.Errorf("invalid depth %q", args[1])
+		}
+		depth = d
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	suite, err := game.ReadEPD(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+
+	results, passed := game.RunEPDSuite(suite, depth)
+	for i, res := range results {
+		status := "FAIL"
+		if res.Passed {
+			status = "ok"
+		}
+		if res.Err != nil {
+			fmt.Printf("%-4s %-12s error: %v\n", status, res.ID, res.Err)
+			continue
+		}
+		fmt.Printf("%-4s %-12s played %-7s expected %s\n", status, res.ID, res.SAN, expectedAnswer(suite[i]))
+	}
+	fmt.Printf("\nScore: %d/%d at depth %d\n", passed, len(results), depth)
+	return nil
+}
+
+// expectedAnswer describes the bm/am operations of a test position
+func expectedAnswer(e *game.EPD) string {
+	var parts []string
+	if bm, ok := e.Operation("bm"); ok {
+		parts = append(parts, "bm "+strings.Join(bm, " "))
+	}
+	if am, ok := e.Operation("am"); ok {
+		parts = append(parts, "am "+strings.Join(am, " "))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -55,15 +55,15 @@ func (g *Game) GetBestMove(depth int) (Move, error) {
 	alpha := -Infinity
 	beta := Infinity
 
+	// Search a copy of the position, playing and taking back moves in place
+	pos := g.position()
+
 	for _, move := range legalMoves {
-		// Clone game state effectively by using MakeMove/Undo logic manually or copy
-		// Since MakeMove alters state, we need a clone or careful Undo.
-		// For simplicity/safety, we will use the Clone method.
-		tempGame := g.Clone()
-		tempGame.MakeMove(move)
+		snapshot := pos.playMove(move)
 
 		// Call Minimax for opponent
-		score := -tempGame.minimax(depth-1, alpha, beta, false)
+		score := -pos.minimax(depth-1, alpha, beta, false)
+		pos.retractMove(snapshot)
 
 		if score > bestScore {
 			bestScore = score
@@ -81,13 +81,25 @@ func (g *Game) GetBestMove(depth int) (Move, error) {
 }
 
 func (g *Game) minimax(depth int, alpha, beta int, isMaximizing bool) int {
+	// Variant wins (king on the hill, third check) end the line like mate
+	if g.Variant != nil {
+		if o := g.Variant.Outcome(g); o.IsOver() {
+			if winner, ok := o.Winner(); !ok {
+				return 0
+			} else if winner == g.Turn {
+				return Infinity - (MaxDepth - depth)
+			}
+			return -Infinity + (MaxDepth - depth)
+		}
+	}
+
 	if depth == 0 {
 		return g.Evaluate()
 	}
 
 	legalMoves := g.GenerateLegalMoves()
 	if len(legalMoves) == 0 {
-		if g.Board.InCheck(g.Turn) {
+		if g.InCheck() {
 			return -Infinity + (MaxDepth - depth) // Prefer faster checkmates
 		}
 		return 0 // Stalemate
@@ -96,9 +108,9 @@ func (g *Game) minimax(depth int, alpha, beta int, isMaximizing bool) int {
 	if isMaximizing {
 		maxEval := -Infinity
 		for _, move := range legalMoves {
-			tempGame := g.Clone()
-			tempGame.MakeMove(move)
-			eval := tempGame.minimax(depth-1, alpha, beta, false)
+			snapshot := g.playMove(move)
+			eval := g.minimax(depth-1, alpha, beta, false)
+			g.retractMove(snapshot)
 			if eval > maxEval {
 				maxEval = eval
 			}
@@ -113,11 +125,11 @@ func (g *Game) minimax(depth int, alpha, beta int, isMaximizing bool) int {
 	} else {
 		minEval := Infinity
 		for _, move := range legalMoves {
-			tempGame := g.Clone()
-			tempGame.MakeMove(move)
+			snapshot := g.playMove(move)
 			// Negamax flip: score returns from opponent perspective, so we don't negate here
 			// Standard minimax:
-			eval := tempGame.minimax(depth-1, alpha, beta, true)
+			eval := g.minimax(depth-1, alpha, beta, true)
+			g.retractMove(snapshot)
 			if eval < minEval {
 				minEval = eval
 			}
@@ -155,20 +167,43 @@ func (g *Game) Evaluate() int {
 		}
 	}
 
+	// Pieces in hand in Crazyhouse count at their value
+	for pt, n := range g.Pockets[White] {
+		whiteScore += pieceValues[PieceType(pt)] * n
+	}
+	for pt, n := range g.Pockets[Black] {
+		blackScore += pieceValues[PieceType(pt)] * n
+	}
+
 	score := whiteScore - blackScore
+	if !g.rules().RoyalKing() {
+		score = -score // In Antichess material is a burden
+	}
 	if g.Turn == Black {
 		return -score // Return score relative to current player
 	}
 	return score
 }
 
-// Clone creates a deep copy of the game for the AI calculation
+// Clone creates a deep copy of the game. The copy does not keep a move
+// tree, so playing moves on it leaves no trace in the game.
 func (g *Game) Clone() *Game {
 	newG := &Game{
 		Board:           g.Board, // Array copy is by value in Go
+		bb:              g.bb,
 		Turn:            g.Turn,
 		EnPassantTarget: g.EnPassantTarget,
 		Castling:        g.Castling,
+		Chess960:        g.Chess960,
+		Variant:         g.Variant,
+		Checks:          g.Checks,
+		Pockets:         g.Pockets,
+		promoted:        g.promoted,
+		castlingRooks:   g.castlingRooks,
+		HalfmoveClock:   g.HalfmoveClock,
+		FullmoveNumber:  g.FullmoveNumber,
+		hash:            g.hash,
+		declared:        g.declared,
 	}
 	// Copy slices
 	newG.History = make([]Move, len(g.History))
@@ -177,5 +212,8 @@ func (g *Game) Clone() *Game {
 	newG.StateHistory = make([]StateSnapshot, len(g.StateHistory))
 	copy(newG.StateHistory, g.StateHistory)
 
+	newG.positionHashes = make([]uint64, len(g.positionHashes))
+	copy(newG.positionHashes, g.positionHashes)
+
 	return newG
 }
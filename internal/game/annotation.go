@@ -0,0 +1,171 @@
+package game
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Numeric Annotation Glyphs for the traditional move suffixes
+const (
+	NAGGoodMove        = 1 // !
+	NAGMistake         = 2 // ?
+	NAGBrilliantMove   = 3 // !!
+	NAGBlunder         = 4 // ??
+	NAGSpeculativeMove = 5 // !?
+	NAGDubiousMove     = 6 // ?!
+)
+
+// glyphNAGs maps suffix annotations to their NAG numbers
+var glyphNAGs = map[string]int{
+	"!":  NAGGoodMove,
+	"?":  NAGMistake,
+	"!!": NAGBrilliantMove,
+	"??": NAGBlunder,
+	"!?": NAGSpeculativeMove,
+	"?!": NAGDubiousMove,
+}
+
+// Command is an embedded PGN command such as [%clk 0:05:00] or [%eval 0.35]
+type Command struct {
+	Name string
+	Args string
+}
+
+// Annotation holds the commentary attached to a move
+type Annotation struct {
+	Comment  string
+	NAGs     []int
+	Commands []Command
+}
+
+// IsEmpty reports whether the annotation carries no information
+func (a *Annotation) IsEmpty() bool {
+	return a.Comment == "" && len(a.NAGs) == 0 && len(a.Commands) == 0
+}
+
+// AddNAG attaches a Numeric Annotation Glyph, ignoring duplicates
+func (a *Annotation) AddNAG(nag int) {
+	for _, n := range a.NAGs {
+		if n == nag {
+			return
+		}
+	}
+	a.NAGs = append(a.NAGs, nag)
+}
+
+// Command returns the arguments of the named command
+func (a *Annotation) Command(name string) (string, bool) {
+	for _, c := range a.Commands {
+		if c.Name == name {
+			return c.Args, true
+		}
+	}
+	return "", false
+}
+
+// SetCommand adds the named command or replaces its arguments
+func (a *Annotation) SetCommand(name, args string) {
+	for i, c := range a.Commands {
+		if c.Name == name {
+			a.Commands[i].Args = args
+			return
+		}
+	}
+	a.Commands = append(a.Commands, Command{Name: name, Args: args})
+}
+
+// Clock returns the remaining clock time recorded with [%clk]
+func (a *Annotation) Clock() (time.Duration, bool) {
+	args, ok := a.Command("clk")
+	if !ok {
+		return 0, false
+	}
+
+	parts := strings.Split(args, ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	hours, err1 := strconv.Atoi(parts[0])
+	minutes, err2 := strconv.Atoi(parts[1])
+	seconds, err3 := strconv.ParseFloat(parts[2], 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0, false
+	}
+
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(seconds*float64(time.Second)), true
+}
+
+// SetClock records the remaining clock time as [%clk H:MM:SS]
+func (a *Annotation) SetClock(d time.Duration) {
+	d = d.Round(time.Second)
+	h := int(d / time.Hour)
+	m := int(d % time.Hour / time.Minute)
+	s := int(d % time.Minute / time.Second)
+	a.SetCommand("clk", fmt.Sprintf("%d:%02d:%02d", h, m, s))
+}
+
+// Eval returns the engine evaluation recorded with [%eval], in pawns from
+// White's point of view, or a mate distance such as "#-3"
+func (a *Annotation) Eval() (string, bool) {
+	return a.Command("eval")
+}
+
+// SetEval records an engine evaluation in pawns from White's point of view
+func (a *Annotation) SetEval(pawns float64) {
+	a.SetCommand("eval", strconv.FormatFloat(pawns, 'f', 2, 64))
+}
+
+// commandPattern finds embedded commands inside a comment
+var commandPattern = regexp.MustCompile(`\[%(\w+)\s+([^\]]*)\]`)
+
+// addComment splits a PGN comment into embedded commands and free text
+func (a *Annotation) addComment(text string) {
+	for _, match := range commandPattern.FindAllStringSubmatch(text, -1) {
+		a.SetCommand(match[1], strings.Join(strings.Fields(match[2]), " "))
+	}
+
+	text = strings.Join(strings.Fields(commandPattern.ReplaceAllString(text, " ")), " ")
+	if text == "" {
+		return
+	}
+	if a.Comment != "" {
+		a.Comment += " "
+	}
+	a.Comment += text
+}
+
+// pgnTokens returns the movetext tokens for the annotation: NAGs followed by
+// a brace comment holding the commands and the comment text
+func (a *Annotation) pgnTokens() []string {
+	var tokens []string
+	for _, nag := range a.NAGs {
+		tokens = append(tokens, fmt.Sprintf("$%d", nag))
+	}
+
+	var words []string
+	for _, c := range a.Commands {
+		words = append(words, strings.Fields(fmt.Sprintf("[%%%s %s]", c.Name, c.Args))...)
+	}
+	// A '}' would end the comment early
+	words = append(words, strings.Fields(strings.ReplaceAll(a.Comment, "}", ")"))...)
+
+	if len(words) > 0 {
+		words[0] = "{" + words[0]
+		words[len(words)-1] += "}"
+		tokens = append(tokens, words...)
+	}
+	return tokens
+}
+
+// LastAnnotation returns the annotation of the move that led to the current
+// position, or nil at the start of the game
+func (g *Game) LastAnnotation() *Annotation {
+	if g.current == nil || g.current.Parent == nil {
+		return nil
+	}
+	return &g.current.Annotation
+}
@@ -0,0 +1,43 @@
+package game
+
+// Antichess (also called Losing Chess or Giveaway) is won by losing every
+// piece or by having no legal move. Captures are compulsory, and the king
+// is an ordinary piece that can be captured and never castles.
+type Antichess struct{ StandardRules }
+
+func (Antichess) Name() string     { return "Antichess" }
+func (Antichess) StartFEN() string { return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1" }
+func (Antichess) RoyalKing() bool  { return false }
+
+// InsufficientMaterial is never true, since there is nothing to mate
+func (Antichess) InsufficientMaterial(g *Game) bool { return false }
+
+// CanWin is always true, since either side can lose its pieces
+func (Antichess) CanWin(g *Game, c Color) bool { return true }
+
+// FilterMoves keeps only the captures when there is one to make
+func (Antichess) FilterMoves(g *Game, moves []Move) []Move {
+	captures := moves[:0]
+	for _, m := range moves {
+		if g.Board[m.To].Type != Empty || m.MoveType == MoveEnPassant {
+			captures = append(captures, m)
+		}
+	}
+	if len(captures) > 0 {
+		return captures
+	}
+	// No capture was kept, so the moves are untouched
+	return moves
+}
+
+func (Antichess) Outcome(g *Game) Outcome {
+	for _, p := range g.Board {
+		if p.Type != Empty && p.Color == g.Turn {
+			if len(g.GenerateLegalMoves()) == 0 {
+				return Outcome{Result: winFor(g.Turn), Termination: TerminationStalemate}
+			}
+			return Outcome{}
+		}
+	}
+	return Outcome{Result: winFor(g.Turn), Termination: TerminationAllPiecesLost}
+}
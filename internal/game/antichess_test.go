@@ -0,0 +1,123 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAntichessPerft(t *testing.T) {
+	tests := []struct {
+		fen    string
+		counts []uint64
+	}{
+		{Antichess{}.StartFEN(), []uint64{20, 400, 8067, 153299}},
+		{"8/1p6/8/8/8/8/P7/8 w - - 0 1", []uint64{2, 4, 4, 3, 1, 0}},
+		{"8/2p5/8/8/8/8/P7/8 w - - 0 1", []uint64{2, 4, 4, 4, 4, 4, 4, 4}},
+	}
+	for _, tt := range tests {
+		g := NewVariantGame(Antichess{})
+		if err := g.LoadFEN(tt.fen); err != nil {
+			t.Fatal(err)
+		}
+		for i, want := range tt.counts {
+			if testing.Short() && want > 10000 {
+				break
+			}
+			if got := g.Perft(i + 1); got != want {
+				t.Errorf("%s: perft(%d) = %d, want %d", tt.fen, i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestAntichessRules(t *testing.T) {
+	g := NewVariantGame(Antichess{})
+	for _, san := range []string{"e4", "d5"} {
+		m, err := g.ParseSAN(san)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.MakeMove(m)
+	}
+	// Capturing is compulsory
+	moves := g.GenerateLegalMoves()
+	if len(moves) != 1 || g.MoveToSAN(moves[0]) != "exd5" {
+		t.Errorf("Only exd5 should be legal, got %d moves", len(moves))
+	}
+	if _, err := g.ParseSAN("Nf3"); err == nil {
+		t.Error("Nf3 ignores the capture")
+	}
+
+	// The king can be captured, and pawns may promote to king
+	if err := g.LoadFEN("8/1P6/8/8/8/8/8/k3K3 w - - 0 1"); err != nil {
+		t.Fatal(err)
+	}
+	if g.InCheck() {
+		t.Error("There is no check without a royal king")
+	}
+	m, err := g.ParseSAN("b8=K")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.MakeMove(m)
+	if g.Board[57] != (Piece{Type: King, Color: White}) {
+		t.Error("The pawn should have become a king")
+	}
+
+	if err := g.LoadFEN("8/8/8/8/8/8/1k6/K7 w - - 0 1"); err != nil {
+		t.Fatal(err)
+	}
+	m, err = g.ParseSAN("Kxb2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.MakeMove(m)
+	if o := g.Outcome(); o.Result != BlackWins || o.Termination != TerminationAllPiecesLost {
+		t.Errorf("Losing every piece should win, got %v", o)
+	}
+
+	if err := g.LoadFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"); err == nil {
+		t.Error("Castling rights should be rejected in Antichess")
+	}
+}
+
+// Test that an en passant capture exposing the king still counts in the
+// hash, since the king may be left attacked in Antichess
+func TestAntichessEnPassantHash(t *testing.T) {
+	g := NewVariantGame(Antichess{})
+	if err := g.LoadFEN("8/8/8/KPp4r/8/8/8/4k3 w - c6 0 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	withEP := g.Hash()
+	g.EnPassantTarget = -1
+	if g.Hash() == withEP {
+		t.Error("A legal en passant capture should change the position hash")
+	}
+}
+
+// Test that promotions to king can be typed as coordinates
+func TestAntichessKingPromotionInput(t *testing.T) {
+	g := NewVariantGame(Antichess{})
+	if err := g.LoadFEN("8/4P3/8/8/8/8/8/k7 w - - 0 1"); err != nil {
+		t.Fatal(err)
+	}
+	m, err := g.ParseMoveInput("e7e8k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Promotion != King {
+		t.Errorf("e7e8k promotes to %s", m.Promotion)
+	}
+	if _, err := ParseMove("e7e8x", g.GenerateLegalMoves()); err == nil || !strings.Contains(err.Error(), "q, r, b, n or k") {
+		t.Errorf("Error for an invalid promotion piece = %v, want the choices including k", err)
+	}
+
+	g = NewGame()
+	if err := g.LoadFEN("4k3/P7/8/8/8/8/8/4K3 w - - 0 1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.ParseMoveInput("a7a8k"); err == nil {
+		t.Error("Pawns cannot promote to king in standard chess")
+	}
+}
@@ -0,0 +1,120 @@
+package game
+
+// Atomic makes every capture an explosion: the capturing piece, the
+// captured one and every piece but pawns next to it leave the board. Kings
+// cannot capture, and a king next to the enemy king cannot be in check,
+// since taking it would blow up both. Blowing up the enemy king wins.
+type Atomic struct{ StandardRules }
+
+func (Atomic) Name() string   { return "Atomic" }
+func (Atomic) Explodes() bool { return true }
+
+// CanWin reports whether c has a piece besides the king, which cannot
+// capture, to blow up or mate the enemy king with
+func (Atomic) CanWin(g *Game, c Color) bool { return hasPieceBesidesKing(&g.Board, c) }
+
+func (Atomic) Outcome(g *Game) Outcome {
+	for _, c := range []Color{White, Black} {
+		if g.Board.kingSquare(c) < 0 {
+			return Outcome{Result: winFor(c.Opponent()), Termination: TerminationExplosion}
+		}
+	}
+	return Outcome{}
+}
+
+// explode removes the piece on sq and every piece but pawns next to it
+func (bb *bitboards) explode(sq int) {
+	blast := kingAttacks[sq]&^(bb.pieces[White][Pawn]|bb.pieces[Black][Pawn]) | squareBB(sq)
+	for c := range bb.pieces {
+		for pt := range bb.pieces[c] {
+			bb.pieces[c][pt] &^= blast
+		}
+		bb.colors[c] &^= blast
+	}
+	bb.all &^= blast
+}
+
+// atomicInCheck reports whether the king of color c is attacked, which it
+// never is while the kings touch
+func (bb *bitboards) atomicInCheck(c Color) bool {
+	king, enemy := bb.pieces[c][King], bb.pieces[c.Opponent()][King]
+	if king == 0 || enemy == 0 || kingAttacks[king.LSB()]&enemy != 0 {
+		return false
+	}
+	return bb.inCheck(c)
+}
+
+// survivesExplosion reports whether color c may have made the move that led
+// to the position: its king must still stand, and must be out of check
+// unless the enemy king was blown up
+func (bb *bitboards) survivesExplosion(c Color) bool {
+	if bb.pieces[c][King] == 0 {
+		return false
+	}
+	return bb.pieces[c.Opponent()][King] == 0 || !bb.atomicInCheck(c)
+}
+
+// explode blows up the piece that just captured on sq and the pieces but
+// pawns around it, giving up the castling rights of any king or castling
+// rook among them. It returns the pieces removed, four bits each, for
+// unexplode: the squares around sq in order, then sq itself.
+func (g *Game) explode(sq int) uint64 {
+	var packed uint64
+	blast := kingAttacks[sq]
+	for i := 0; i <= 8; i++ {
+		s := sq
+		if i < 8 {
+			if blast == 0 {
+				continue
+			}
+			s = blast.PopLSB()
+		}
+		p := g.Board[s]
+		if p.Type == Empty || (p.Type == Pawn && s != sq) {
+			continue
+		}
+
+		packed |= uint64(p.Type|PieceType(p.Color)<<3) << (4 * uint(i))
+		g.hash ^= zobristPiece(p, s)
+		g.setSquare(s, Piece{Type: Empty})
+
+		if p.Type == King {
+			*g.Castling.right(p.Color, true) = false
+			*g.Castling.right(p.Color, false) = false
+		}
+		for side, rook := range g.castlingRooks[p.Color] {
+			if s == rook {
+				*g.Castling.right(p.Color, side == 0) = false
+			}
+		}
+	}
+	return packed
+}
+
+// unexplode puts back the pieces explode removed around sq
+func (g *Game) unexplode(sq int, packed uint64) {
+	blast := kingAttacks[sq]
+	for i := 0; i <= 8; i++ {
+		s := sq
+		if i < 8 {
+			if blast == 0 {
+				continue
+			}
+			s = blast.PopLSB()
+		}
+		if bits := packed >> (4 * uint(i)) & 0xF; bits != 0 {
+			g.setSquare(s, Piece{Type: PieceType(bits & 7), Color: Color(bits >> 3)})
+		}
+	}
+}
+
+// explodedPieces lists the pieces packed by explode
+func explodedPieces(packed uint64) []Piece {
+	var pieces []Piece
+	for ; packed != 0; packed >>= 4 {
+		if bits := packed & 0xF; bits != 0 {
+			pieces = append(pieces, Piece{Type: PieceType(bits & 7), Color: Color(bits >> 3)})
+		}
+	}
+	return pieces
+}
@@ -0,0 +1,90 @@
+package game
+
+import "testing"
+
+func TestAtomicPerft(t *testing.T) {
+	tests := []struct {
+		fen      string
+		chess960 bool
+		counts   []uint64
+	}{
+		{StartFEN, false, []uint64{20, 400, 8902, 197326}},
+		{"rn2kb1r/1pp1p2p/p2q1pp1/3P4/2P3b1/4PN2/PP3PPP/R2QKB1R b KQkq - 0 1", false, []uint64{40, 1238, 45237}},
+		{"rn1qkb1r/p5pp/2p5/3p4/N3P3/5P2/PPP4P/R1BQK3 w Qkq - 0 1", false, []uint64{28, 833, 23353}},
+		{"8/8/8/8/8/8/2k5/rR4KR w KQ - 0 1", true, []uint64{18, 180, 4364, 61401}},
+	}
+	for _, tt := range tests {
+		g := NewVariantGame(Atomic{})
+		g.Chess960 = tt.chess960
+		if err := g.LoadFEN(tt.fen); err != nil {
+			t.Fatal(err)
+		}
+		for i, want := range tt.counts {
+			if testing.Short() && want > 10000 {
+				break
+			}
+			if got := g.Perft(i + 1); got != want {
+				t.Errorf("%s: perft(%d) = %d, want %d", tt.fen, i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestAtomicExplosion(t *testing.T) {
+	g := NewVariantGame(Atomic{})
+	if err := g.LoadFEN("4k3/8/4b3/3np3/8/4N3/8/4K3 w - - 0 1"); err != nil {
+		t.Fatal(err)
+	}
+	before := g.Hash()
+
+	m, err := g.ParseSAN("Nxd5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.MakeMove(m)
+	// The knight, d5 and the bishop on e6 go; the pawn on e5 stays
+	if g.FEN() != "4k3/8/8/4p3/8/8/8/4K3 b - - 0 1" {
+		t.Errorf("FEN after the explosion = %s", g.FEN())
+	}
+	checkHash(t, g, 1)
+	want := []Piece{{Type: Knight, Color: Black}, {Type: Bishop, Color: Black}, {Type: Knight, Color: White}}
+	if got := g.CapturedPieces(); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("Expected the blown up pieces %v to count as captured, got %v", want, got)
+	}
+
+	g.UndoMove()
+	if g.Hash() != before || g.FEN() != "4k3/8/4b3/3np3/8/4N3/8/4K3 w - - 0 1" {
+		t.Errorf("Undo should bring the pieces back, got %s", g.FEN())
+	}
+}
+
+func TestAtomicKings(t *testing.T) {
+	g := NewVariantGame(Atomic{})
+	if err := g.LoadFEN("8/8/8/8/8/4k3/3r4/4K3 w - - 0 1"); err != nil {
+		t.Fatal(err)
+	}
+	// Kings touching cannot be in check, and kings never capture
+	if g.InCheck() {
+		t.Error("A king next to the enemy king is not in check")
+	}
+	for _, m := range g.GenerateLegalMoves() {
+		if m.To == 11 {
+			t.Error("The king must not capture the rook on d2")
+		}
+	}
+
+	if err := g.LoadFEN("4k3/4r3/8/8/8/8/8/4RK2 w - - 0 1"); err != nil {
+		t.Fatal(err)
+	}
+	m, err := g.ParseSAN("Rxe7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.MakeMove(m)
+	if o := g.Outcome(); o.Result != WhiteWins || o.Termination != TerminationExplosion {
+		t.Errorf("Blowing up the king should win, got %v", o)
+	}
+	if moves := g.GenerateLegalMoves(); len(moves) != 0 {
+		t.Errorf("The game is over, but Black has %d moves", len(moves))
+	}
+}
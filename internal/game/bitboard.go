@@ -0,0 +1,300 @@
+package game
+
+import "math/bits"
+
+// Bitboard is a set of squares, with bit i standing for square i (a1 = 0, h8 = 63)
+type Bitboard uint64
+
+// squareBB returns the set holding only sq
+func squareBB(sq int) Bitboard {
+	return 1 << uint(sq)
+}
+
+// Has reports whether sq is in the set
+func (b Bitboard) Has(sq int) bool {
+	return b&squareBB(sq) != 0
+}
+
+// Count returns the number of squares in the set
+func (b Bitboard) Count() int {
+	return bits.OnesCount64(uint64(b))
+}
+
+// LSB returns the lowest square in a non-empty set
+func (b Bitboard) LSB() int {
+	return bits.TrailingZeros64(uint64(b))
+}
+
+// PopLSB removes the lowest square from a non-empty set and returns it
+func (b *Bitboard) PopLSB() int {
+	sq := b.LSB()
+	*b &= *b - 1
+	return sq
+}
+
+// Precomputed attack sets, indexed by square
+var (
+	knightAttacks [64]Bitboard
+	kingAttacks   [64]Bitboard
+	pawnAttacks   [2][64]Bitboard // Squares a pawn of the color attacks from the square
+	rookMagics    [64]magic
+	bishopMagics  [64]magic
+	between       [64][64]Bitboard // Squares strictly between two squares on a shared rank, file or diagonal
+	line          [64][64]Bitboard // The whole rank, file or diagonal two squares share
+)
+
+// File and rank deltas of each piece's moves
+var (
+	knightOffsets    = [][2]int{{1, 2}, {1, -2}, {-1, 2}, {-1, -2}, {2, 1}, {2, -1}, {-2, 1}, {-2, -1}}
+	kingOffsets      = [][2]int{{0, 1}, {0, -1}, {1, 0}, {-1, 0}, {1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+	rookDirections   = [][2]int{{0, 1}, {0, -1}, {1, 0}, {-1, 0}}
+	bishopDirections = [][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+)
+
+// magic maps the blockers on a slider's rays to its attack set with one
+// multiplication and shift ("magic bitboards")
+type magic struct {
+	mask    Bitboard // Squares whose occupancy can change the attacks
+	magic   uint64
+	shift   uint
+	attacks []Bitboard
+}
+
+func (m *magic) index(occupied Bitboard) uint64 {
+	return (uint64(occupied&m.mask) * m.magic) >> m.shift
+}
+
+// rookAttacks returns the squares a rook on sq attacks given the occupied squares
+func rookAttacks(sq int, occupied Bitboard) Bitboard {
+	m := &rookMagics[sq]
+	return m.attacks[m.index(occupied)]
+}
+
+// bishopAttacks returns the squares a bishop on sq attacks given the occupied squares
+func bishopAttacks(sq int, occupied Bitboard) Bitboard {
+	m := &bishopMagics[sq]
+	return m.attacks[m.index(occupied)]
+}
+
+// pieceAttacks returns the attacks of a non-pawn piece on sq
+func pieceAttacks(pt PieceType, sq int, occupied Bitboard) Bitboard {
+	switch pt {
+	case Knight:
+		return knightAttacks[sq]
+	case Bishop:
+		return bishopAttacks(sq, occupied)
+	case Rook:
+		return rookAttacks(sq, occupied)
+	case Queen:
+		return rookAttacks(sq, occupied) | bishopAttacks(sq, occupied)
+	case King:
+		return kingAttacks[sq]
+	}
+	return 0
+}
+
+func init() {
+	for sq := 0; sq < 64; sq++ {
+		knightAttacks[sq] = stepAttacks(sq, knightOffsets)
+		kingAttacks[sq] = stepAttacks(sq, kingOffsets)
+		pawnAttacks[White][sq] = stepAttacks(sq, [][2]int{{-1, 1}, {1, 1}})
+		pawnAttacks[Black][sq] = stepAttacks(sq, [][2]int{{-1, -1}, {1, -1}})
+	}
+
+	for sq := 0; sq < 64; sq++ {
+		rookMagics[sq] = initMagic(sq, rookDirections, rookMagicNumbers[sq])
+		bishopMagics[sq] = initMagic(sq, bishopDirections, bishopMagicNumbers[sq])
+	}
+
+	for a := 0; a < 64; a++ {
+		for b := 0; b < 64; b++ {
+			if a == b {
+				continue
+			}
+			for _, attacks := range [2]func(int, Bitboard) Bitboard{rookAttacks, bishopAttacks} {
+				if attacks(a, 0).Has(b) {
+					between[a][b] = attacks(a, squareBB(b)) & attacks(b, squareBB(a))
+					line[a][b] = attacks(a, 0)&attacks(b, 0) | squareBB(a) | squareBB(b)
+				}
+			}
+		}
+	}
+}
+
+// stepAttacks returns the squares one step away from sq along each offset
+func stepAttacks(sq int, offsets [][2]int) Bitboard {
+	var attacks Bitboard
+	for _, off := range offsets {
+		file, rank := sq%8+off[0], sq/8+off[1]
+		if file >= 0 && file < 8 && rank >= 0 && rank < 8 {
+			attacks |= squareBB(rank*8 + file)
+		}
+	}
+	return attacks
+}
+
+// slidingAttacks walks each ray from sq up to and including the first
+// occupied square. It is only used to fill the magic tables.
+func slidingAttacks(sq int, occupied Bitboard, directions [][2]int) Bitboard {
+	var attacks Bitboard
+	for _, dir := range directions {
+		file, rank := sq%8+dir[0], sq/8+dir[1]
+		for file >= 0 && file < 8 && rank >= 0 && rank < 8 {
+			target := rank*8 + file
+			attacks |= squareBB(target)
+			if occupied.Has(target) {
+				break
+			}
+			file, rank = file+dir[0], rank+dir[1]
+		}
+	}
+	return attacks
+}
+
+// relevantMask returns the ray squares that can block, leaving out the last
+// square of each ray since a piece there never hides anything behind it
+func relevantMask(sq int, directions [][2]int) Bitboard {
+	var mask Bitboard
+	for _, dir := range directions {
+		file, rank := sq%8+dir[0], sq/8+dir[1]
+		for {
+			nextFile, nextRank := file+dir[0], rank+dir[1]
+			if nextFile < 0 || nextFile > 7 || nextRank < 0 || nextRank > 7 {
+				break
+			}
+			mask |= squareBB(rank*8 + file)
+			file, rank = nextFile, nextRank
+		}
+	}
+	return mask
+}
+
+// initMagic fills the attack table of a slider on sq for every subset of
+// blockers in its mask
+func initMagic(sq int, directions [][2]int, number uint64) magic {
+	mask := relevantMask(sq, directions)
+	n := mask.Count()
+	m := magic{mask: mask, magic: number, shift: uint(64 - n), attacks: make([]Bitboard, 1<<uint(n))}
+
+	// Enumerate all subsets of the mask (Carry-Rippler)
+	var subset Bitboard
+	for {
+		m.attacks[m.index(subset)] = slidingAttacks(sq, subset, directions)
+		subset = (subset - mask) & mask
+		if subset == 0 {
+			break
+		}
+	}
+	return m
+}
+
+// bitboards holds a position as one set of squares per color and piece type
+type bitboards struct {
+	pieces [2][7]Bitboard // Indexed by Color and PieceType
+	colors [2]Bitboard
+	all    Bitboard
+}
+
+// bitboards converts the mailbox board into piece sets
+func (b *Board) bitboards() bitboards {
+	var bb bitboards
+	for sq, p := range b {
+		if p.Type != Empty {
+			bb.pieces[p.Color][p.Type] |= squareBB(sq)
+			bb.colors[p.Color] |= squareBB(sq)
+		}
+	}
+	bb.all = bb.colors[White] | bb.colors[Black]
+	return bb
+}
+
+// SetBoard replaces the pieces on the board. Writes to Board itself are not
+// seen by move generation, which works on piece sets kept up to date by each
+// move, so a position set up square by square must be passed in here.
+func (g *Game) SetBoard(b Board) {
+	g.Board = b
+	g.bb = b.bitboards()
+}
+
+// setSquare puts p on sq, which may be empty, in both Board and the piece sets
+func (g *Game) setSquare(sq int, p Piece) {
+	bit := squareBB(sq)
+	if old := g.Board[sq]; old.Type != Empty {
+		g.bb.pieces[old.Color][old.Type] &^= bit
+		g.bb.colors[old.Color] &^= bit
+	}
+	if p.Type != Empty {
+		g.bb.pieces[p.Color][p.Type] |= bit
+		g.bb.colors[p.Color] |= bit
+	}
+	g.bb.all = g.bb.colors[White] | g.bb.colors[Black]
+	g.Board[sq] = p
+}
+
+// isAttacked reports whether any piece of color by attacks sq
+func (bb *bitboards) isAttacked(sq int, by Color) bool {
+	them := &bb.pieces[by]
+	return pawnAttacks[by.Opponent()][sq]&them[Pawn] != 0 ||
+		knightAttacks[sq]&them[Knight] != 0 ||
+		kingAttacks[sq]&them[King] != 0 ||
+		bishopAttacks(sq, bb.all)&(them[Bishop]|them[Queen]) != 0 ||
+		rookAttacks(sq, bb.all)&(them[Rook]|them[Queen]) != 0
+}
+
+// inCheck reports whether the king of color c is attacked
+func (bb *bitboards) inCheck(c Color) bool {
+	king := bb.pieces[c][King]
+	if king == 0 {
+		return false
+	}
+	return bb.isAttacked(king.LSB(), c.Opponent())
+}
+
+// play applies m for the side to move in g to the sets. g must still be in
+// the position before the move, which tells which piece is captured.
+func (bb *bitboards) play(g *Game, m Move) {
+	c := g.Turn
+	from, to := squareBB(m.From), squareBB(m.To)
+
+	if m.MoveType == MoveDrop {
+		bb.pieces[c][m.Piece] |= to
+		bb.colors[c] |= to
+		bb.all |= to
+		return
+	}
+
+	if m.MoveType == MoveCastling {
+		rookFrom, rookTo := g.castlingRookSquares(m)
+		rook := squareBB(rookFrom)
+		bb.pieces[c][King] = to
+		bb.pieces[c][Rook] = bb.pieces[c][Rook]&^rook | squareBB(rookTo)
+		bb.colors[c] = bb.colors[c]&^(from|rook) | to | squareBB(rookTo)
+		bb.all = bb.colors[White] | bb.colors[Black]
+		return
+	}
+
+	if captured := g.Board[m.To]; captured.Type != Empty {
+		bb.pieces[captured.Color][captured.Type] &^= to
+		bb.colors[captured.Color] &^= to
+	}
+	if m.MoveType == MoveEnPassant {
+		captureSq := enPassantCaptureSquare(m.To, c)
+		bb.pieces[c.Opponent()][Pawn] &^= squareBB(captureSq)
+		bb.colors[c.Opponent()] &^= squareBB(captureSq)
+	}
+
+	moving := g.Board[m.From].Type
+	placed := moving
+	if m.Promotion != Empty {
+		placed = m.Promotion
+	}
+	bb.pieces[c][moving] &^= from
+	bb.pieces[c][placed] |= to
+	bb.colors[c] = bb.colors[c]&^from | to
+
+	bb.all = bb.colors[White] | bb.colors[Black]
+
+	if (g.Board[m.To].Type != Empty || m.MoveType == MoveEnPassant) && g.rules().Explodes() {
+		bb.explode(m.To)
+	}
+}
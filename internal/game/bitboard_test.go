@@ -0,0 +1,82 @@
+package game
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Test that the magic lookups agree with walking the rays
+func TestSlidingAttackTables(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for sq := 0; sq < 64; sq++ {
+		for i := 0; i < 200; i++ {
+			occupied := Bitboard(rng.Uint64() & rng.Uint64())
+			if got, want := rookAttacks(sq, occupied), slidingAttacks(sq, occupied, rookDirections); got != want {
+				t.Fatalf("rook on %s: got %x, want %x", IndexToCoord(sq), got, want)
+			}
+			if got, want := bishopAttacks(sq, occupied), slidingAttacks(sq, occupied, bishopDirections); got != want {
+				t.Fatalf("bishop on %s: got %x, want %x", IndexToCoord(sq), got, want)
+			}
+		}
+	}
+}
+
+// Test the piece sets built from the mailbox board
+func TestBoardBitboards(t *testing.T) {
+	g := NewGame()
+	bb := g.Board.bitboards()
+
+	if bb.all.Count() != 32 || bb.colors[White] != 0xFFFF || bb.colors[Black] != 0xFFFF<<48 {
+		t.Errorf("Unexpected occupancy %x", bb.all)
+	}
+	if bb.pieces[White][King] != squareBB(4) || bb.pieces[Black][Queen] != squareBB(59) {
+		t.Error("Kings and queens not on their starting squares")
+	}
+	if !bb.isAttacked(21, White) || bb.isAttacked(28, White) {
+		t.Error("Expected f3 attacked and e4 unattacked by White")
+	}
+}
+
+// Test that the piece sets kept on the game follow every move and take-back,
+// including castling, en passant, promotions, explosions and drops
+func TestPieceSetsFollowMoves(t *testing.T) {
+	tests := []struct {
+		variant Variant
+		fen     string
+	}{
+		{StandardRules{}, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1"},
+		{StandardRules{}, "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1"},
+		{Atomic{}, "rn1qkb1r/p5pp/2p5/3p4/N3P3/5P2/PPP4P/R1BQK3 w Qkq - 0 1"},
+		{Crazyhouse{}, "2k5/8/8/8/8/8/8/4K3[QRBNPqrbnp] w - - 0 1"},
+	}
+	var walk func(g *Game, depth int)
+	walk = func(g *Game, depth int) {
+		if g.bb != g.Board.bitboards() {
+			t.Fatalf("%s: piece sets out of step with the board", g.FEN())
+		}
+		if depth == 0 {
+			return
+		}
+		for _, m := range g.GenerateLegalMoves() {
+			snapshot := g.playMove(m)
+			walk(g, depth-1)
+			g.retractMove(snapshot)
+		}
+	}
+	for _, tt := range tests {
+		g := NewVariantGame(tt.variant)
+		if err := g.LoadFEN(tt.fen); err != nil {
+			t.Fatalf("%s: %v", tt.fen, err)
+		}
+		walk(g, 2)
+	}
+
+	g := NewGame()
+	var board Board
+	board[4] = Piece{Type: King, Color: White}
+	board[60] = Piece{Type: King, Color: Black}
+	g.SetBoard(board)
+	if g.bb.all != squareBB(4)|squareBB(60) {
+		t.Errorf("SetBoard left occupancy %x", g.bb.all)
+	}
+}
@@ -2,154 +2,127 @@ package game
 
 // InCheck returns true if the King of the given color is under attack
 func (b *Board) InCheck(color Color) bool {
-	// 1. Find the King
-	kingPos := -1
-	for i := 0; i < 64; i++ {
-		piece := b[i]
-		if piece.Type == King && piece.Color == color {
-			kingPos = i
-			break
-		}
-	}
-
-	// Should not happen in a valid game, but safeguard
-	if kingPos == -1 {
-		return false
-	}
-
-	// 2. Check if that square is attacked by the opponent
-	enemyColor := Black
-	if color == Black {
-		enemyColor = White
-	}
-
-	return b.IsSquareAttacked(kingPos, enemyColor)
+	bb := b.bitboards()
+	return bb.inCheck(color)
 }
 
 // IsSquareAttacked checks if 'sq' is attacked by pieces of 'attackerColor'
 func (b *Board) IsSquareAttacked(sq int, attackerColor Color) bool {
-	rank := sq / 8
-	file := sq % 8
-
-	// 1. Check for Pawn attacks
-	pawnDir := -1
-	if attackerColor == White {
-		pawnDir = 1 // White pawns attack upward (from lower ranks)
-	}
+	bb := b.bitboards()
+	return bb.isAttacked(sq, attackerColor)
+}
 
-	checkRank := rank - pawnDir
-	if checkRank >= 0 && checkRank < 8 {
-		for _, fileOffset := range []int{-1, 1} {
-			checkFile := file + fileOffset
-			if checkFile >= 0 && checkFile < 8 {
-				idx := checkRank*8 + checkFile
-				target := b[idx]
-				if target.Type == Pawn && target.Color == attackerColor {
-					return true
-				}
-			}
+// Attackers returns the squares of the pieces of color by that attack sq, in
+// the order they can take part in an exchange there: the direct attackers,
+// least valuable first, then the sliders behind them (x-ray), which join in
+// once the pieces in front of them have captured on sq.
+func (b *Board) Attackers(sq int, by Color) []int {
+	bb := b.bitboards()
+	var squares []int
+	occupied := bb.all
+	for {
+		attackers := bb.attackersTo(sq, occupied) & occupied
+		if attackers == 0 {
+			return squares
 		}
-	}
-
-	// 2. Check for Knight attacks
-	knightMoves := [][2]int{
-		{-2, -1}, {-2, 1}, {-1, -2}, {-1, 2},
-		{1, -2}, {1, 2}, {2, -1}, {2, 1},
-	}
-	for _, move := range knightMoves {
-		checkRank := rank + move[0]
-		checkFile := file + move[1]
-		if checkRank >= 0 && checkRank < 8 && checkFile >= 0 && checkFile < 8 {
-			idx := checkRank*8 + checkFile
-			target := b[idx]
-			if target.Type == Knight && target.Color == attackerColor {
-				return true
+		for pt := Pawn; pt <= King; pt++ {
+			for set := attackers & bb.pieces[by][pt]; set != 0; {
+				squares = append(squares, set.PopLSB())
 			}
 		}
+		// Lift the attackers of both colors to uncover the pieces behind them
+		occupied &^= attackers
 	}
+}
 
-	// 3. Check for Sliding attacks (Rook/Queen) - Orthogonal
-	directions := [][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
-	for _, dir := range directions {
-		if b.checkSlidingAttack(sq, dir, attackerColor, true) {
-			return true
-		}
+// Defenders returns the squares of the pieces guarding the piece on sq, which
+// are its own side's attackers, or nil when sq is empty
+func (b *Board) Defenders(sq int) []int {
+	p := b[sq]
+	if p.Type == Empty {
+		return nil
 	}
+	return b.Attackers(sq, p.Color)
+}
 
-	// 4. Check for Sliding attacks (Bishop/Queen) - Diagonal
-	directions = [][2]int{{-1, -1}, {-1, 1}, {1, -1}, {1, 1}}
-	for _, dir := range directions {
-		if b.checkSlidingAttack(sq, dir, attackerColor, false) {
-			return true
-		}
-	}
+// attackersTo returns the pieces of both colors that attack sq, with the
+// sliders blocked by the squares in occupied
+func (bb *bitboards) attackersTo(sq int, occupied Bitboard) Bitboard {
+	w, b := &bb.pieces[White], &bb.pieces[Black]
+	return pawnAttacks[Black][sq]&w[Pawn] |
+		pawnAttacks[White][sq]&b[Pawn] |
+		knightAttacks[sq]&(w[Knight]|b[Knight]) |
+		kingAttacks[sq]&(w[King]|b[King]) |
+		bishopAttacks(sq, occupied)&(w[Bishop]|b[Bishop]|w[Queen]|b[Queen]) |
+		rookAttacks(sq, occupied)&(w[Rook]|b[Rook]|w[Queen]|b[Queen])
+}
 
-	// 5. Check for King attacks (1 square in all directions)
-	kingMoves := [][2]int{
-		{-1, -1}, {-1, 0}, {-1, 1},
-		{0, -1}, {0, 1},
-		{1, -1}, {1, 0}, {1, 1},
+// SEE returns the static exchange evaluation of m for the side to move, in
+// centipawns: the material it wins or loses once both sides have recaptured
+// on m.To with their least valuable pieces for as long as that pays off.
+// Pins are ignored, and a royal king only recaptures when the square is no
+// longer attacked. Quiet moves score how much the moving piece hangs.
+func (g *Game) SEE(m Move) int {
+	if m.MoveType == MoveCastling {
+		return 0
 	}
-	for _, move := range kingMoves {
-		checkRank := rank + move[0]
-		checkFile := file + move[1]
-		if checkRank >= 0 && checkRank < 8 && checkFile >= 0 && checkFile < 8 {
-			idx := checkRank*8 + checkFile
-			target := b[idx]
-			if target.Type == King && target.Color == attackerColor {
-				return true
-			}
-		}
+	bb := &g.bb
+	royal := g.rules().RoyalKing()
+	to := m.To
+	lastRank := to/8 == 0 || to/8 == 7
+
+	// gain[d] is the balance for the side making capture d if the exchange
+	// stops there
+	var gain [32]int
+	gain[0] = pieceValues[g.Board[to].Type]
+	moving := g.Board[m.From].Type
+	if m.MoveType == MoveDrop {
+		moving = m.Piece
+	}
+	occupied := bb.all &^ squareBB(m.From)
+	if m.MoveType == MoveEnPassant {
+		gain[0] = pieceValues[Pawn]
+		occupied &^= squareBB(enPassantCaptureSquare(to, g.Turn))
+	}
+	onSquare := pieceValues[moving]
+	if m.Promotion != Empty {
+		gain[0] += pieceValues[m.Promotion] - pieceValues[Pawn]
+		onSquare = pieceValues[m.Promotion]
 	}
 
-	return false
-}
-
-// checkSlidingAttack checks for sliding piece attacks in a given direction
-// isOrthogonal: true for rook-like moves, false for bishop-like moves
-func (b *Board) checkSlidingAttack(startSq int, direction [2]int, attackerColor Color, isOrthogonal bool) bool {
-	rank := startSq / 8
-	file := startSq % 8
-
-	dRank := direction[0]
-	dFile := direction[1]
-
-	for i := 1; i < 8; i++ {
-		checkRank := rank + (dRank * i)
-		checkFile := file + (dFile * i)
-
-		// Out of bounds
-		if checkRank < 0 || checkRank > 7 || checkFile < 0 || checkFile > 7 {
+	side := g.Turn.Opponent()
+	d := 0
+	for d < len(gain)-1 {
+		attackers := bb.attackersTo(to, occupied) & occupied
+		ours := attackers & bb.colors[side]
+		if ours == 0 {
 			break
 		}
-
-		idx := checkRank*8 + checkFile
-		target := b[idx]
-
-		// Empty square, continue sliding
-		if target.Type == Empty {
-			continue
-		}
-
-		// Found a piece
-		if target.Color == attackerColor {
-			// Check if it's the right type of attacker
-			if isOrthogonal {
-				// Orthogonal: Rook or Queen
-				if target.Type == Rook || target.Type == Queen {
-					return true
-				}
-			} else {
-				// Diagonal: Bishop or Queen
-				if target.Type == Bishop || target.Type == Queen {
-					return true
-				}
+		pt, from := Pawn, 0
+		for ; pt <= King; pt++ {
+			if set := ours & bb.pieces[side][pt]; set != 0 {
+				from = set.LSB()
+				break
 			}
 		}
+		occupied &^= squareBB(from)
+		if pt == King && royal && bb.attackersTo(to, occupied)&occupied&bb.colors[side.Opponent()] != 0 {
+			break
+		}
 
-		// Blocked by any piece (same color or opponent)
-		break
+		d++
+		gain[d] = onSquare - gain[d-1]
+		onSquare = pieceValues[pt]
+		if pt == Pawn && lastRank {
+			gain[d] += pieceValues[Queen] - pieceValues[Pawn]
+			onSquare = pieceValues[Queen]
+		}
+		side = side.Opponent()
 	}
 
-	return false
+	// Each side stops capturing when carrying on would leave it worse off
+	for ; d > 0; d-- {
+		gain[d-1] = -max(-gain[d-1], gain[d])
+	}
+	return gain[0]
 }
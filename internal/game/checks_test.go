@@ -0,0 +1,66 @@
+package game
+
+import (
+	"slices"
+	"testing"
+)
+
+func squares(coords ...string) []int {
+	var sqs []int
+	for _, c := range coords {
+		sqs = append(sqs, CoordToIndex(c))
+	}
+	return sqs
+}
+
+// Test that attackers come in exchange order, x-rays last
+func TestAttackers(t *testing.T) {
+	g := NewGame()
+	if err := g.LoadFEN("1k1r3q/1ppn3p/p4b2/4p3/8/P2N2P1/1PP1R1BP/2K1Q3 w - - 0 1"); err != nil {
+		t.Fatal(err)
+	}
+	e5 := CoordToIndex("e5")
+	if got, want := g.Board.Attackers(e5, White), squares("d3", "e2", "e1"); !slices.Equal(got, want) {
+		t.Errorf("White attackers of e5 = %v, want %v", got, want)
+	}
+	if got, want := g.Board.Defenders(e5), squares("d7", "f6", "h8"); !slices.Equal(got, want) {
+		t.Errorf("Defenders of e5 = %v, want %v", got, want)
+	}
+	if got := g.Board.Defenders(CoordToIndex("e4")); got != nil {
+		t.Errorf("Defenders of an empty square = %v", got)
+	}
+}
+
+func TestSEE(t *testing.T) {
+	tests := []struct {
+		fen  string
+		move string
+		want int
+	}{
+		{"1k1r4/1pp4p/p7/4p3/8/P5P1/1PP4P/2K1R3 w - - 0 1", "Rxe5", 100},
+		{"1k1r3q/1ppn3p/p4b2/4p3/8/P2N2P1/1PP1R1BP/2K1Q3 w - - 0 1", "Nxe5", -220},
+		{"4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1", "exd5", 100},
+		{"4k3/8/2p5/3p4/4P3/8/8/4K3 w - - 0 1", "exd5", 0},
+		{"2k5/8/2p5/3p4/4Q3/8/8/4K3 w - - 0 1", "Qxd5", -800},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "exd6", 100},
+		{"3rk3/2P5/8/8/8/8/8/4K3 w - - 0 1", "cxd8=Q+", 400},
+		{"4k3/8/8/8/8/8/5p2/4R2K b - - 0 1", "fxe1=Q+", 1300},
+		{"4k3/3p4/8/8/8/8/3Q4/4K3 w - - 0 1", "Qxd7+", -800},
+		{"4k3/3p4/8/8/8/8/3Q4/3RK3 w - - 0 1", "Qxd7+", 100},
+		{"4k3/8/8/8/8/8/3p4/4K3 w - - 0 1", "Kxd2", 100},
+		{"1k6/8/8/8/8/8/8/R3K3 w - - 0 1", "Ra8+", -500},
+	}
+	for _, tt := range tests {
+		g := NewGame()
+		if err := g.LoadFEN(tt.fen); err != nil {
+			t.Fatalf("%s: %v", tt.fen, err)
+		}
+		m, err := g.ParseSAN(tt.move)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.fen, err)
+		}
+		if got := g.SEE(m); got != tt.want {
+			t.Errorf("%s %s: SEE = %d, want %d", tt.fen, tt.move, got, tt.want)
+		}
+	}
+}
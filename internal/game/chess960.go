@@ -0,0 +1,181 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Chess960 (Fischer Random) starts from one of 960 back rank arrangements:
+// bishops on opposite colors and the king between the rooks. Castling
+// still puts the king on the g- or c-file and the rook on the f- or d-file,
+// wherever they started.
+
+// standardCastlingRooks are the rook squares of normal chess, by Color and
+// side (king side first)
+var standardCastlingRooks = [2][2]int{{7, 0}, {63, 56}}
+
+// chess960Knights lists the files, among the five left after the bishops
+// and queen, taken by the knights for each remainder of the index
+var chess960Knights = [10][2]int{{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2}, {1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4}}
+
+// Chess960FEN returns the start position with the given index (0-959) in
+// the standard numbering, where 518 is the normal chess start position
+func Chess960FEN(index int) (string, error) {
+	if index < 0 || index > 959 {
+		return "", fmt.Errorf("Chess960 position must be between 0 and 959, got %d", index)
+	}
+
+	var rank [8]PieceType
+	// placeOnEmpty puts pt on the nth still empty file
+	placeOnEmpty := func(pt PieceType, n int) {
+		for file := range rank {
+			if rank[file] != Empty {
+				continue
+			}
+			if n == 0 {
+				rank[file] = pt
+				return
+			}
+			n--
+		}
+	}
+
+	n := index
+	rank[2*(n%4)+1] = Bishop // Light-squared bishop on b, d, f or h
+	n /= 4
+	rank[2*(n%4)] = Bishop // Dark-squared bishop on a, c, e or g
+	n /= 4
+	placeOnEmpty(Queen, n%6)
+	n /= 6
+	knights := chess960Knights[n]
+	placeOnEmpty(Knight, knights[1]) // The later file first, so the earlier one still counts the same empties
+	placeOnEmpty(Knight, knights[0])
+	placeOnEmpty(Rook, 0)
+	placeOnEmpty(King, 0)
+	placeOnEmpty(Rook, 0)
+
+	var back strings.Builder
+	for _, pt := range rank {
+		back.WriteString(Piece{Type: pt, Color: White}.String())
+	}
+	white := back.String()
+	return strings.ToLower(white) + "/pppppppp/8/8/8/8/PPPPPPPP/" + white + " w KQkq - 0 1", nil
+}
+
+// NewChess960Game returns a Chess960 game starting from the position with
+// the given index
+func NewChess960Game(index int) (*Game, error) {
+	fen, err := Chess960FEN(index)
+	if err != nil {
+		return nil, err
+	}
+	g := NewGame()
+	g.Chess960 = true
+	if err := g.LoadFEN(fen); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// right returns the flag for color c castling on the king or queen side
+func (r *CastlingRights) right(c Color, kingSide bool) *bool {
+	switch {
+	case c == White && kingSide:
+		return &r.WhiteKingSide
+	case c == White:
+		return &r.WhiteQueenSide
+	case kingSide:
+		return &r.BlackKingSide
+	default:
+		return &r.BlackQueenSide
+	}
+}
+
+func castlingSideIndex(kingSide bool) int {
+	if kingSide {
+		return 0
+	}
+	return 1
+}
+
+// castlingRook returns the square the rook castling with color c on the
+// side starts from
+func (g *Game) castlingRook(c Color, kingSide bool) int {
+	return g.castlingRooks[c][castlingSideIndex(kingSide)]
+}
+
+// castlingTargets returns where the king and the rook land when castling on
+// the side along the back rank of rook
+func castlingTargets(rook int, kingSide bool) (kingTo, rookTo int) {
+	backRank := rook &^ 7
+	if kingSide {
+		return backRank + 6, backRank + 5
+	}
+	return backRank + 2, backRank + 3
+}
+
+// castlingRookSquares returns where the rook starts and ends for the
+// castling move m, whose king lands on the g- or c-file
+func (g *Game) castlingRookSquares(m Move) (int, int) {
+	c := White
+	if m.To >= 56 {
+		c = Black
+	}
+	kingSide := m.To%8 == 6
+	rook := g.castlingRook(c, kingSide)
+	_, rookTo := castlingTargets(rook, kingSide)
+	return rook, rookTo
+}
+
+// rankSpan returns the squares from a to b inclusive, which must share a rank
+func rankSpan(a, b int) Bitboard {
+	if a > b {
+		a, b = b, a
+	}
+	return Bitboard(uint64(1)<<uint(b-a+1)-1) << uint(a)
+}
+
+// kingSquare returns the square of the king of color c, or -1 if there is none
+func (b *Board) kingSquare(c Color) int {
+	for sq, p := range b {
+		if p == (Piece{Type: King, Color: c}) {
+			return sq
+		}
+	}
+	return -1
+}
+
+// outermostRook returns the rook of color c furthest from the king on its
+// side of the back rank, or -1 if there is none
+func outermostRook(board *Board, c Color, king int, kingSide bool) int {
+	step, sq := 1, king&^7
+	if kingSide {
+		step, sq = -1, sq+7
+	}
+	for ; sq != king; sq += step {
+		if board[sq] == (Piece{Type: Rook, Color: c}) {
+			return sq
+		}
+	}
+	return -1
+}
+
+// castlingByRook maps the king and rook of a castling move played as
+// king-takes-rook, the way Chess960 writes castling in UCI, to the legal
+// castling move
+func (g *Game) castlingByRook(from, to int, legalMoves []Move) (Move, bool) {
+	if g.Board[from] != (Piece{Type: King, Color: g.Turn}) {
+		return Move{}, false
+	}
+	for _, kingSide := range []bool{true, false} {
+		if !*g.Castling.right(g.Turn, kingSide) || g.castlingRook(g.Turn, kingSide) != to {
+			continue
+		}
+		for _, m := range legalMoves {
+			if m.MoveType == MoveCastling && (m.To%8 == 6) == kingSide {
+				return m, true
+			}
+		}
+	}
+	return Move{}, false
+}
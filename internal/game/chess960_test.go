@@ -0,0 +1,153 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChess960FEN(t *testing.T) {
+	tests := []struct {
+		index int
+		fen   string
+	}{
+		{0, "bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w KQkq - 0 1"},
+		{518, StartFEN},
+		{959, "rkrnnqbb/pppppppp/8/8/8/8/PPPPPPPP/RKRNNQBB w KQkq - 0 1"},
+	}
+	for _, tt := range tests {
+		fen, err := Chess960FEN(tt.index)
+		if err != nil || fen != tt.fen {
+			t.Errorf("Chess960FEN(%d) = %q, %v; want %q", tt.index, fen, err, tt.fen)
+		}
+	}
+
+	// Every index gives a distinct legal start position
+	seen := map[string]bool{}
+	for i := 0; i < 960; i++ {
+		g, err := NewChess960Game(i)
+		if err != nil {
+			t.Fatalf("position %d: %v", i, err)
+		}
+		if seen[g.FEN()] {
+			t.Fatalf("position %d repeats %s", i, g.FEN())
+		}
+		seen[g.FEN()] = true
+	}
+
+	if _, err := Chess960FEN(960); err == nil {
+		t.Error("Index 960 should be rejected")
+	}
+}
+
+// Test that a Chess960 game stays one when no castling rights are left
+func TestChess960FENWithoutCastling(t *testing.T) {
+	g, err := NewChess960Game(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.LoadFEN("bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w - - 0 1"); err != nil {
+		t.Fatal(err)
+	}
+	if !g.Chess960 {
+		t.Error("Loading a FEN without castling rights turned the game into standard chess")
+	}
+	if pgn := g.GeneratePGN(); !strings.Contains(pgn, `[Variant "Chess960"]`) {
+		t.Errorf("PGN lost the variant tag:\n%s", pgn)
+	}
+}
+
+// Perft counts from the Chess960 perft suite
+func TestChess960Perft(t *testing.T) {
+	tests := []struct {
+		fen   string
+		nodes []uint64
+	}{
+		{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", []uint64{21, 528, 12189}},
+		{"2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9", []uint64{21, 807, 18002}},
+		{"b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9", []uint64{20, 479, 10471}},
+		{"qbbnnrkr/2pp2pp/p7/1p2pp2/8/P3PP2/1PPP1KPP/QBBNNR1R w hf - 0 9", []uint64{22, 593, 13440}},
+		{"1nbbnrkr/p1p1ppp1/3p4/1p3P1p/3Pq2P/8/PPP1P1P1/QNBBNRKR w HFhf - 0 9", []uint64{28, 1120, 31058}},
+	}
+	for _, tt := range tests {
+		g := NewGame()
+		if err := g.LoadFEN(tt.fen); err != nil {
+			t.Fatalf("%s: %v", tt.fen, err)
+		}
+		checkHash(t, g, 2)
+		for depth, want := range tt.nodes {
+			if testing.Short() && want > 10000 {
+				continue
+			}
+			if got := g.Perft(depth + 1); got != want {
+				t.Errorf("%s: perft(%d) = %d, want %d", tt.fen, depth+1, got, want)
+			}
+		}
+	}
+}
+
+func TestChess960Castling(t *testing.T) {
+	g := NewGame()
+	if err := g.LoadFEN("r3k2r/8/8/8/8/8/8/R3KR1R w FAkq - 0 1"); err != nil {
+		t.Fatal(err)
+	}
+	if !g.Chess960 {
+		t.Fatal("Rook file letters should switch the game to Chess960")
+	}
+	if want := "r3k2r/8/8/8/8/8/8/R3KR1R w FQkq - 0 1"; g.FEN() != want {
+		t.Errorf("FEN() = %q, want X-FEN %q", g.FEN(), want)
+	}
+
+	// Castling with the inner f1 rook, whose square the king passes over
+	m, err := g.ParseMoveInput("e1f1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.MoveType != MoveCastling || g.UCI(m) != "e1f1" || g.MoveToSAN(m) != "O-O" {
+		t.Fatalf("e1f1 should castle king side, got %+v (%s)", m, g.MoveToSAN(m))
+	}
+	// The h1 rook has no castling right, so e1h1 is no castling move
+	if _, err := ParseMove("e1h1", g.GenerateLegalMoves()); err == nil {
+		t.Error("e1h1 should not castle with the f1 rook")
+	}
+	g.MakeMove(m)
+	if g.Board[6].Type != King || g.Board[5].Type != Rook || g.Board[7].Type != Rook || g.Board[4].Type != Empty {
+		t.Errorf("Unexpected board after castling: %s", g.FEN())
+	}
+	g.UndoMove()
+	if want := "r3k2r/8/8/8/8/8/8/R3KR1R w FQkq - 0 1"; g.FEN() != want {
+		t.Errorf("Undo should restore %q, got %q", want, g.FEN())
+	}
+
+	// The king and rook swap squares
+	g.LoadFEN("4k3/8/8/8/8/8/8/5KR1 w G - 0 1")
+	if _, err := g.ParseMoveInput("f1g1"); err != nil {
+		t.Fatal(err)
+	}
+	m, _ = g.ParseSAN("O-O")
+	g.MakeMove(m)
+	if g.Board[6] != (Piece{Type: King, Color: White}) || g.Board[5] != (Piece{Type: Rook, Color: White}) {
+		t.Errorf("Expected Kg1 and Rf1 after castling, got %s", g.FEN())
+	}
+}
+
+func TestChess960PGNRoundTrip(t *testing.T) {
+	g, err := NewChess960Game(518)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, san := range []string{"e4", "e5", "Nf3", "Nf6", "Bc4", "Bc5", "O-O", "O-O"} {
+		m, err := g.ParseSAN(san)
+		if err != nil {
+			t.Fatalf("%s: %v", san, err)
+		}
+		g.MakeMove(m)
+	}
+
+	parsed, err := ParsePGN(g.GeneratePGN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.Chess960 || parsed.FEN() != g.FEN() {
+		t.Errorf("Round trip gave %s (Chess960 %v), want %s", parsed.FEN(), parsed.Chess960, g.FEN())
+	}
+}
@@ -0,0 +1,155 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Crazyhouse puts every captured piece in the capturer's pocket. Instead of
+// moving, a player may drop a piece from the pocket onto any empty square,
+// except pawns on the first or last rank. A promoted piece goes back to the
+// pocket as a pawn.
+type Crazyhouse struct{ StandardRules }
+
+func (Crazyhouse) Name() string { return "Crazyhouse" }
+func (Crazyhouse) StartFEN() string {
+	return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1"
+}
+func (Crazyhouse) HasPockets() bool { return true }
+
+// Material never runs out, since captured pieces come back, so neither side
+// is ever short of it
+func (Crazyhouse) InsufficientMaterial(g *Game) bool { return false }
+func (Crazyhouse) CanWin(g *Game, c Color) bool      { return true }
+
+// pocketPieces lists the pieces that can be in a pocket, in FEN order
+var pocketPieces = []PieceType{Queen, Rook, Bishop, Knight, Pawn}
+
+// maxPocketCount bounds the pieces of one type in hand: every piece but the
+// kings
+const maxPocketCount = 30
+
+// backRanks holds the first and last ranks, where pawns cannot be dropped
+const backRanks = Bitboard(0xFF) | Bitboard(0xFF)<<56
+
+// dropMoves appends a drop of each piece in the pocket of the side to move
+// onto every empty square it may go to
+func (g *Game) dropMoves(bb *bitboards, moves []Move) []Move {
+	for _, pt := range pocketPieces {
+		if g.Pockets[g.Turn][pt] == 0 {
+			continue
+		}
+		targets := ^bb.all
+		if pt == Pawn {
+			targets &^= backRanks
+		}
+		for targets != 0 {
+			sq := targets.PopLSB()
+			moves = append(moves, Move{From: sq, To: sq, Piece: pt, MoveType: MoveDrop})
+		}
+	}
+	return moves
+}
+
+// pocketMove updates the pockets and the promoted pieces for m, played by
+// the side to move, which captured the piece that stood on m.To
+func (g *Game) pocketMove(m Move, captured Piece) {
+	switch {
+	case m.MoveType == MoveEnPassant:
+		g.Pockets[g.Turn][Pawn]++
+	case m.MoveType == MoveDrop:
+		g.Pockets[g.Turn][m.Piece]--
+		return
+	case captured.Type != Empty && g.promoted.Has(m.To):
+		g.Pockets[g.Turn][Pawn]++
+	case captured.Type != Empty:
+		g.Pockets[g.Turn][captured.Type]++
+	}
+
+	wasPromoted := g.promoted.Has(m.From)
+	g.promoted &^= squareBB(m.From) | squareBB(m.To)
+	if wasPromoted || m.Promotion != Empty {
+		g.promoted |= squareBB(m.To)
+	}
+}
+
+// unpocketMove takes back the pocket changes of the move in snapshot
+func (g *Game) unpocketMove(snapshot StateSnapshot) {
+	m, captured := snapshot.Move, snapshot.Captured
+	switch {
+	case m.MoveType == MoveEnPassant:
+		g.Pockets[snapshot.Turn][Pawn]--
+	case m.MoveType == MoveDrop:
+		g.Pockets[snapshot.Turn][m.Piece]++
+	case captured.Type != Empty && snapshot.Promoted.Has(m.To):
+		g.Pockets[snapshot.Turn][Pawn]--
+	case captured.Type != Empty:
+		g.Pockets[snapshot.Turn][captured.Type]--
+	}
+}
+
+// splitPocket separates the bracketed pocket from a placement field, e.g.
+// "RNBQKBNR[Nnp]", which is optional
+func splitPocket(placement string) (string, string, error) {
+	open := strings.IndexByte(placement, '[')
+	if open < 0 {
+		return placement, "", nil
+	}
+	if !strings.HasSuffix(placement, "]") {
+		return "", "", fenError("pocket", "%q is not closed by ']'", placement[open:])
+	}
+	return placement[:open], placement[open+1 : len(placement)-1], nil
+}
+
+// parsePocket reads the pieces in hand, White's in upper case and Black's
+// in lower case, e.g. "Nnp"
+func parsePocket(field string) ([2][7]int, error) {
+	var pockets [2][7]int
+	for _, char := range field {
+		p := charToPiece(char)
+		if p.Type == Empty || p.Type == King {
+			return pockets, fenError("pocket", "invalid piece '%c'", char)
+		}
+		pockets[p.Color][p.Type]++
+		if pockets[p.Color][p.Type] > maxPocketCount {
+			return pockets, fenError("pocket", "more than %d of '%c'", maxPocketCount, char)
+		}
+	}
+	return pockets, nil
+}
+
+// pocketField writes the pockets as they appear in brackets in a FEN
+func (g *Game) pocketField() string {
+	var sb strings.Builder
+	for _, c := range []Color{White, Black} {
+		for _, pt := range pocketPieces {
+			sb.WriteString(strings.Repeat(Piece{Type: pt, Color: c}.String(), g.Pockets[c][pt]))
+		}
+	}
+	return sb.String()
+}
+
+// dropSAN writes a drop as the piece letter, "@" and the square, e.g.
+// "N@f3" or "P@e4". UCI writes drops the same way.
+func dropSAN(m Move) string {
+	return Piece{Type: m.Piece, Color: White}.String() + "@" + IndexToCoord(m.To)
+}
+
+// parseDrop matches a drop such as "N@f3", "P@e4" or "@e4" against the
+// legal moves
+func (g *Game) parseDrop(piece string, square string, legalMoves []Move) (Move, error) {
+	pt := Pawn
+	if piece != "" {
+		pt = charToPiece(rune(piece[0])).Type
+	}
+	to := CoordToIndex(square)
+	for _, m := range legalMoves {
+		if m.MoveType == MoveDrop && m.Piece == pt && m.To == to {
+			return m, nil
+		}
+	}
+	if g.Pockets[g.Turn][pt] == 0 {
+		return Move{}, fmt.Errorf("%w: %s has no %s in the pocket", ErrIllegalMove, g.Turn, strings.ToLower(pt.String()))
+	}
+	return Move{}, fmt.Errorf("%w: %s cannot drop a %s on %s", ErrIllegalMove, g.Turn, strings.ToLower(pt.String()), square)
+}
@@ -0,0 +1,136 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCrazyhousePerft(t *testing.T) {
+	tests := []struct {
+		fen    string
+		counts []uint64
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1", []uint64{20, 400, 8902, 197281}},
+		{"2k5/8/8/8/8/8/8/4K3[QRBNPqrbnp] w - - 0 1", []uint64{301, 75353}},
+	}
+	for _, tt := range tests {
+		g := NewVariantGame(Crazyhouse{})
+		if err := g.LoadFEN(tt.fen); err != nil {
+			t.Fatal(err)
+		}
+		for i, want := range tt.counts {
+			if testing.Short() && want > 10000 {
+				break
+			}
+			if got := g.Perft(i + 1); got != want {
+				t.Errorf("%s: perft(%d) = %d, want %d", tt.fen, i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestCrazyhouseDrops(t *testing.T) {
+	g := NewVariantGame(Crazyhouse{})
+	for _, san := range []string{"e4", "d5", "exd5", "Qxd5"} {
+		m, err := g.ParseSAN(san)
+		if err != nil {
+			t.Fatalf("%s: %v", san, err)
+		}
+		g.MakeMove(m)
+	}
+	if g.Pockets[White][Pawn] != 1 || g.Pockets[Black][Pawn] != 1 {
+		t.Fatalf("Each side should hold a pawn, got %v", g.Pockets)
+	}
+	if !strings.HasPrefix(g.FEN(), "rnb1kbnr/ppp1pppp/8/3q4/8/8/PPPP1PPP/RNBQKBNR[Pp] w") {
+		t.Errorf("FEN = %s", g.FEN())
+	}
+
+	if _, err := g.ParseSAN("N@f3"); err == nil {
+		t.Error("White has no knight to drop")
+	}
+	if _, err := g.ParseSAN("P@e1"); err == nil {
+		t.Error("Pawns cannot be dropped on the first rank")
+	}
+
+	before := g.Hash()
+	m, err := g.ParseMoveInput("P@d4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if san := g.MoveToSAN(m); san != "P@d4" {
+		t.Errorf("SAN = %s, want P@d4", san)
+	}
+	if uci := g.UCI(m); uci != "P@d4" {
+		t.Errorf("UCI = %s, want P@d4", uci)
+	}
+	g.MakeMove(m)
+	if g.Board[27] != (Piece{Type: Pawn, Color: White}) || g.Pockets[White][Pawn] != 0 {
+		t.Error("The pawn should move from the pocket to d4")
+	}
+	checkHash(t, g, 2)
+
+	g.UndoMove()
+	if g.Hash() != before || g.Pockets[White][Pawn] != 1 || g.Board[27].Type != Empty {
+		t.Error("Undo should put the pawn back in the pocket")
+	}
+}
+
+func TestCrazyhouseDropOutOfCheck(t *testing.T) {
+	g := NewVariantGame(Crazyhouse{})
+	if err := g.LoadFEN("4k3/8/8/8/8/8/8/r3K3[N] w - - 0 1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range g.GenerateLegalMoves() {
+		if m.MoveType == MoveDrop && m.To != 1 && m.To != 2 && m.To != 3 {
+			t.Errorf("Drop on %s does not block the check", IndexToCoord(m.To))
+		}
+	}
+}
+
+func TestCrazyhousePromotedPieceDemotes(t *testing.T) {
+	g := NewVariantGame(Crazyhouse{})
+	if err := g.LoadFEN("1n5r/P4k2/8/8/8/8/8/4K3[] w - - 0 1"); err != nil {
+		t.Fatal(err)
+	}
+	g.MakeMove(Move{From: 48, To: 57, Piece: Pawn, Promotion: Queen}) // axb8=Q
+	if g.Pockets[White][Knight] != 1 {
+		t.Error("The captured knight should go to White's pocket")
+	}
+	if fen := g.FEN(); !strings.HasPrefix(fen, "1Q~5r/") {
+		t.Errorf("FEN should mark the promoted queen, got %s", fen)
+	}
+
+	loaded := NewVariantGame(Crazyhouse{})
+	if err := loaded.LoadFEN(g.FEN()); err != nil || loaded.FEN() != g.FEN() {
+		t.Fatalf("FEN round trip: %v, %s", err, loaded.FEN())
+	}
+
+	g.MakeMove(Move{From: 63, To: 57, Piece: Rook}) // Rxb8
+	if g.Pockets[Black][Pawn] != 1 || g.Pockets[Black][Queen] != 0 {
+		t.Errorf("The promoted queen should go to Black's pocket as a pawn, got %v", g.Pockets[Black])
+	}
+	checkHash(t, g, 1)
+}
+
+func TestCrazyhousePGNRoundTrip(t *testing.T) {
+	g := NewVariantGame(Crazyhouse{})
+	for _, san := range []string{"e4", "d5", "exd5", "Nf6", "P@e4", "Nxd5", "c4", "Nb4", "a3", "N4c6", "d4"} {
+		m, err := g.ParseSAN(san)
+		if err != nil {
+			t.Fatalf("%s: %v", san, err)
+		}
+		g.MakeMove(m)
+	}
+
+	pgn := g.GeneratePGN()
+	if !strings.Contains(pgn, `[Variant "Crazyhouse"]`) || !strings.Contains(pgn, "3. P@e4") {
+		t.Fatalf("PGN should name the variant and write the drop:\n%s", pgn)
+	}
+	parsed, err := ParsePGN(pgn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.FEN() != g.FEN() {
+		t.Errorf("FEN after round trip = %s, want %s", parsed.FEN(), g.FEN())
+	}
+}
@@ -0,0 +1,130 @@
+package game
+
+// Move-count draw limits, measured in plies (half-moves)
+const (
+	FiftyMoveRulePlies       = 100
+	SeventyFiveMoveRulePlies = 150
+)
+
+// CanClaimFiftyMoveDraw reports whether fifty moves by each side have been played
+// without a capture or pawn move, which lets either player claim a draw.
+// A checkmate delivered on the final move takes precedence, so callers should
+// check for checkmate first.
+func (g *Game) CanClaimFiftyMoveDraw() bool {
+	return g.HalfmoveClock >= FiftyMoveRulePlies
+}
+
+// IsSeventyFiveMoveDraw reports whether the game is automatically drawn because
+// seventy-five moves by each side have passed without a capture or pawn move.
+func (g *Game) IsSeventyFiveMoveDraw() bool {
+	return g.HalfmoveClock >= SeventyFiveMoveRulePlies
+}
+
+// Repetition thresholds
+const (
+	ThreefoldRepetition = 3
+	FivefoldRepetition  = 5
+)
+
+// RepetitionCount returns how many times the current position has occurred,
+// including the current occurrence
+func (g *Game) RepetitionCount() int {
+	if len(g.positionHashes) == 0 {
+		return 1
+	}
+
+	current := g.positionHashes[len(g.positionHashes)-1]
+	count := 0
+
+	// Positions before the last capture or pawn move can never repeat,
+	// so only scan back as far as the halfmove clock allows
+	oldest := len(g.positionHashes) - 1 - g.HalfmoveClock
+	if oldest < 0 {
+		oldest = 0
+	}
+	for i := len(g.positionHashes) - 1; i >= oldest; i-- {
+		if g.positionHashes[i] == current {
+			count++
+		}
+	}
+	return count
+}
+
+// CanClaimThreefoldRepetition reports whether the current position has
+// occurred at least three times, which lets either player claim a draw
+func (g *Game) CanClaimThreefoldRepetition() bool {
+	return g.RepetitionCount() >= ThreefoldRepetition
+}
+
+// IsFivefoldRepetition reports whether the current position has occurred
+// five times, which draws the game automatically
+func (g *Game) IsFivefoldRepetition() bool {
+	return g.RepetitionCount() >= FivefoldRepetition
+}
+
+// hasLegalEnPassant reports whether the side to move can capture en passant.
+// A pawn must stand ready to capture; only then are the legal moves
+// generated, so that the variant decides, e.g. Antichess, where the capture
+// may leave the king attacked.
+func (g *Game) hasLegalEnPassant() bool {
+	if g.EnPassantTarget < 0 {
+		return false
+	}
+
+	// Capturing pawns stand beside the double-stepped pawn, one rank behind the target
+	fromRank := g.EnPassantTarget/8 - 1
+	if g.Turn == Black {
+		fromRank = g.EnPassantTarget/8 + 1
+	}
+	targetFile := g.EnPassantTarget % 8
+
+	for _, file := range []int{targetFile - 1, targetFile + 1} {
+		if file < 0 || file > 7 || fromRank < 0 || fromRank > 7 {
+			continue
+		}
+		from := fromRank*8 + file
+		if p := g.Board[from]; p.Type == Pawn && p.Color == g.Turn {
+			for _, m := range g.GenerateLegalMoves() {
+				if m.MoveType == MoveEnPassant {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return false
+}
+
+// IsInsufficientMaterial reports whether neither side can possibly checkmate,
+// which draws the game automatically. The dead positions recognised are
+// K v K, K+N v K, K+B v K and any number of bishops that all stand on
+// squares of the same colour.
+func (b *Board) IsInsufficientMaterial() bool {
+	knights := 0
+	bishops := 0
+	bishopSquareColors := [2]bool{}
+
+	for sq, p := range b {
+		switch p.Type {
+		case Pawn, Rook, Queen:
+			return false
+		case Knight:
+			knights++
+		case Bishop:
+			bishops++
+			bishopSquareColors[(sq/8+sq%8)%2] = true
+		}
+	}
+
+	// Kings only, or a single minor piece
+	if knights+bishops <= 1 {
+		return true
+	}
+
+	// Bishops only, all on the same colour
+	if knights == 0 {
+		return !(bishopSquareColors[0] && bishopSquareColors[1])
+	}
+
+	return false
+}
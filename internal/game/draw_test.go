@@ -0,0 +1,228 @@
+package game
+
+import (
+	"testing"
+)
+
+// Test that the move clocks are parsed, advanced and restored by undo
+func TestMoveClocks(t *testing.T) {
+	g := NewGame()
+	g.LoadFEN("4k3/8/8/8/8/8/4P3/4K1N1 w - - 12 30")
+
+	if g.HalfmoveClock != 12 || g.FullmoveNumber != 30 {
+		t.Fatalf("Expected clocks 12/30 from FEN, got %d/%d", g.HalfmoveClock, g.FullmoveNumber)
+	}
+
+	g.MakeMove(Move{From: 6, To: 21, Piece: Knight}) // Ng1-f3
+	if g.HalfmoveClock != 13 || g.FullmoveNumber != 30 {
+		t.Errorf("Expected clocks 13/30 after a knight move, got %d/%d", g.HalfmoveClock, g.FullmoveNumber)
+	}
+
+	g.MakeMove(Move{From: 60, To: 59, Piece: King}) // Ke8-d8
+	if g.FullmoveNumber != 31 {
+		t.Errorf("Fullmove number should advance after Black moves, got %d", g.FullmoveNumber)
+	}
+
+	g.MakeMove(Move{From: 12, To: 28, Piece: Pawn}) // e2-e4
+	if g.HalfmoveClock != 0 {
+		t.Errorf("Pawn move should reset the halfmove clock, got %d", g.HalfmoveClock)
+	}
+
+	g.UndoMove()
+	g.UndoMove()
+	g.UndoMove()
+	if g.HalfmoveClock != 12 || g.FullmoveNumber != 30 {
+		t.Errorf("Undo should restore clocks 12/30, got %d/%d", g.HalfmoveClock, g.FullmoveNumber)
+	}
+}
+
+// Test the fifty-move and seventy-five-move thresholds
+func TestFiftyMoveRule(t *testing.T) {
+	g := NewGame()
+	g.LoadFEN("4k3/8/8/8/8/8/8/4K1N1 w - - 99 80")
+
+	if g.CanClaimFiftyMoveDraw() {
+		t.Error("Fifty-move draw should not be claimable after 99 plies")
+	}
+
+	g.MakeMove(Move{From: 6, To: 21, Piece: Knight})
+	if !g.CanClaimFiftyMoveDraw() {
+		t.Error("Fifty-move draw should be claimable after 100 plies")
+	}
+	if g.IsSeventyFiveMoveDraw() {
+		t.Error("Seventy-five-move draw should not apply after 100 plies")
+	}
+
+	g.HalfmoveClock = 150
+	if !g.IsSeventyFiveMoveDraw() {
+		t.Error("Seventy-five-move draw should apply after 150 plies")
+	}
+}
+
+// Test that shuffling knights repeats the position and undo rewinds the count
+func TestRepetitionDetection(t *testing.T) {
+	g := NewGame()
+
+	// Ng1-f3 Ng8-f6 Nf3-g1 Nf6-g8 returns to the start position
+	shuffle := []Move{
+		{From: 6, To: 21, Piece: Knight},
+		{From: 62, To: 45, Piece: Knight},
+		{From: 21, To: 6, Piece: Knight},
+		{From: 45, To: 62, Piece: Knight},
+	}
+
+	for _, m := range shuffle {
+		g.MakeMove(m)
+	}
+	if g.RepetitionCount() != 2 {
+		t.Fatalf("Expected start position to have occurred twice, got %d", g.RepetitionCount())
+	}
+
+	for _, m := range shuffle {
+		g.MakeMove(m)
+	}
+	if !g.CanClaimThreefoldRepetition() {
+		t.Error("Threefold repetition should be claimable")
+	}
+	if g.IsFivefoldRepetition() {
+		t.Error("Fivefold repetition should not apply yet")
+	}
+
+	for i := 0; i < 2; i++ {
+		for _, m := range shuffle {
+			g.MakeMove(m)
+		}
+	}
+	if !g.IsFivefoldRepetition() {
+		t.Error("Fivefold repetition should apply after the fifth occurrence")
+	}
+
+	g.UndoMove()
+	if g.RepetitionCount() != 4 {
+		t.Errorf("Undo should rewind the repetition history, got count %d", g.RepetitionCount())
+	}
+}
+
+// Test that an en passant square only distinguishes positions when the capture is possible
+func TestRepetitionIgnoresImpossibleEnPassant(t *testing.T) {
+	g := NewGame()
+	g.LoadFEN("4k3/8/8/8/8/8/4P3/4K3 w - - 0 1")
+	g.MakeMove(Move{From: 12, To: 28, Piece: Pawn}) // e2-e4, no black pawn to capture
+
+	withEP := g.Hash()
+	g.EnPassantTarget = -1
+	if g.Hash() != withEP {
+		t.Error("En passant square without a capturing pawn should not change the position hash")
+	}
+}
+
+// Test classification of dead positions
+func TestInsufficientMaterial(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		dead bool
+	}{
+		{"K v K", "4k3/8/8/8/8/8/8/4K3 w - - 0 1", true},
+		{"K+N v K", "4k3/8/8/8/8/8/8/4KN2 w - - 0 1", true},
+		{"K+B v K", "4k3/8/8/8/8/8/8/4KB2 w - - 0 1", true},
+		{"K+B v K+B same colour", "4kb2/8/8/8/8/8/8/2B1K3 w - - 0 1", true},
+		{"K+B v K+B opposite colours", "4kb2/8/8/8/8/8/8/3BK3 w - - 0 1", false},
+		{"K+N v K+N", "4kn2/8/8/8/8/8/8/4KN2 w - - 0 1", false},
+		{"K+N+N v K", "4k3/8/8/8/8/8/8/3NKN2 w - - 0 1", false},
+		{"K+B v K+N", "4kn2/8/8/8/8/8/8/4KB2 w - - 0 1", false},
+		{"K+P v K", "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", false},
+		{"K+R v K", "4k3/8/8/8/8/8/8/R3K3 w - - 0 1", false},
+	}
+
+	for _, tt := range tests {
+		g := NewGame()
+		g.LoadFEN(tt.fen)
+		if got := g.Board.IsInsufficientMaterial(); got != tt.dead {
+			t.Errorf("%s: expected insufficient material %v, got %v", tt.name, tt.dead, got)
+		}
+	}
+}
+
+// Test that Outcome reports checkmate, automatic draws and declared results
+func TestOutcome(t *testing.T) {
+	g := NewGame()
+	if g.Outcome().IsOver() {
+		t.Fatal("Start position should not be over")
+	}
+
+	// Fool's mate: f3 e5 g4 Qh4#
+	g.MakeMove(Move{From: 13, To: 21, Piece: Pawn})
+	g.MakeMove(Move{From: 52, To: 36, Piece: Pawn})
+	g.MakeMove(Move{From: 14, To: 30, Piece: Pawn})
+	g.MakeMove(Move{From: 59, To: 31, Piece: Queen})
+
+	outcome := g.Outcome()
+	if outcome.Result != BlackWins || outcome.Termination != TerminationCheckmate {
+		t.Errorf("Expected 0-1 by checkmate, got %s (%s)", outcome.Result, outcome.Termination)
+	}
+	if err := g.Resign(Black); err == nil {
+		t.Error("Should not be able to resign a finished game")
+	}
+
+	g = NewGame()
+	g.LoadFEN("4k3/8/8/8/8/8/8/4KB2 w - - 0 1")
+	if g.Outcome().Termination != TerminationInsufficientMaterial {
+		t.Errorf("Expected insufficient material, got %s", g.Outcome().Termination)
+	}
+
+	g = NewGame()
+	if err := g.ClaimDraw(); err == nil {
+		t.Error("Should not be able to claim a draw in the start position")
+	}
+	if err := g.Resign(White); err != nil {
+		t.Fatalf("Unexpected resign error: %v", err)
+	}
+	if g.Outcome().String() != "Black wins by resignation" {
+		t.Errorf("Unexpected outcome %q", g.Outcome())
+	}
+
+	// A timeout against a lone king is a draw
+	g = NewGame()
+	g.LoadFEN("4k3/8/8/8/8/8/4P3/4K3 w - - 0 1")
+	g.FlagTimeout(White)
+	if g.Outcome().Result != Draw {
+		t.Errorf("Timeout against insufficient material should draw, got %s", g.Outcome().Result)
+	}
+}
+
+// Test that a timeout loses whenever the opponent could still win, counting
+// the pieces of the side that ran out of time as well
+func TestFlagTimeoutMaterial(t *testing.T) {
+	tests := []struct {
+		variant Variant
+		fen     string
+		want    Result
+	}{
+		{nil, "4k3/8/8/8/8/8/4p3/1N2K3 b - - 0 1", WhiteWins},         // K+N vs K+P
+		{nil, "4k3/8/8/8/8/8/4n3/1B2K3 b - - 0 1", WhiteWins},         // K+B vs K+N
+		{nil, "4k3/8/8/8/8/8/7q/1N2K3 b - - 0 1", Draw},               // K+N vs K+Q
+		{nil, "4k3/8/8/8/8/8/8/1N2K3 b - - 0 1", Draw},                // K+N vs K
+		{nil, "4k3/8/8/8/8/4b3/8/2B1K3 b - - 0 1", Draw},              // Bishops on one colour
+		{nil, "4k3/8/8/8/8/3b4/8/2B1K3 b - - 0 1", WhiteWins},         // Bishops on both colours
+		{nil, "4k3/8/8/8/8/8/8/NN2K3 b - - 0 1", WhiteWins},           // Two knights
+		{Crazyhouse{}, "4k3/8/8/8/8/8/8/4K3[N] b - - 0 1", WhiteWins}, // The knight can be dropped
+		{Atomic{}, "4k3/8/8/8/8/8/8/Q3K3 b - - 0 1", WhiteWins},       // A queen can blow up the king
+		{Atomic{}, "4k3/8/8/8/8/8/8/4K3 b - - 0 1", Draw},             // A lone king cannot capture
+		{Antichess{}, "4k3/8/8/8/8/8/8/4K3 b - - 0 1", WhiteWins},
+		{Horde{}, "rnbqkbnr/pppppppp/8/8/8/8/8/7P b kq - 0 1", WhiteWins},
+	}
+	for _, tt := range tests {
+		g := NewGame()
+		if tt.variant != nil {
+			g = NewVariantGame(tt.variant)
+		}
+		if err := g.LoadFEN(tt.fen); err != nil {
+			t.Fatalf("%s: %v", tt.fen, err)
+		}
+		g.FlagTimeout(Black)
+		if got := g.Outcome().Result; got != tt.want {
+			t.Errorf("%s: timeout result %s, want %s", tt.fen, got, tt.want)
+		}
+	}
+}
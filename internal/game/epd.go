@@ -0,0 +1,276 @@
+package game
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// EPDOperation is one opcode of an EPD record with its operands, e.g.
+// bm Qg6 or id "WAC.001"
+type EPDOperation struct {
+	Opcode   string
+	Operands []string
+}
+
+// EPD is a position with the operations attached to it by a test suite
+type EPD struct {
+	Game       *Game
+	Operations []EPDOperation
+}
+
+// ParseEPD reads one EPD record: the first four FEN fields followed by
+// semicolon-terminated operations. The hmc and fmvn opcodes set the move
+// clocks, which EPD otherwise leaves at their defaults.
+func ParseEPD(line string) (*EPD, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return nil, fenError("string", "EPD needs 4 position fields, got %d", len(fields))
+	}
+
+	g := NewGame()
+	if err := g.LoadFEN(strings.Join(fields[:4], " ")); err != nil {
+		return nil, err
+	}
+
+	// Operations start after the fourth field
+	rest := line
+	for i := 0; i < 4; i++ {
+		rest = strings.TrimLeft(rest, " \t")
+		rest = rest[strings.IndexAny(rest+" ", " \t"):]
+	}
+	ops, err := parseEPDOperations(rest)
+	if err != nil {
+		return nil, err
+	}
+	e := &EPD{Game: g, Operations: ops}
+
+	for _, clock := range []struct {
+		opcode string
+		value  *int
+		min    int
+	}{
+		{"hmc", &g.HalfmoveClock, 0},
+		{"fmvn", &g.FullmoveNumber, 1},
+	} {
+		operand, ok := e.Operand(clock.opcode)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(operand)
+		if err != nil || n < clock.min {
+			return nil, fmt.Errorf("invalid EPD operation %s: %q is not a valid count", clock.opcode, operand)
+		}
+		*clock.value = n
+	}
+
+	return e, nil
+}
+
+// parseEPDOperations splits the operation list into opcodes and operands.
+// Quoted operands may contain spaces and semicolons.
+func parseEPDOperations(s string) ([]EPDOperation, error) {
+	var ops []EPDOperation
+	var tokens []string
+	i := 0
+
+	for i < len(s) {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == ';':
+			if len(tokens) == 0 {
+				return nil, fmt.Errorf("invalid EPD operation: empty operation before ';'")
+			}
+			ops = append(ops, EPDOperation{Opcode: tokens[0], Operands: tokens[1:]})
+			tokens = nil
+			i++
+		case c == '"':
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid EPD operation: unterminated string %s", s[i:])
+			}
+			tokens = append(tokens, s[i+1:i+1+end])
+			i += end + 2
+		default:
+			start := i
+			for i < len(s) && !strings.ContainsRune(" \t\r\n;\"", rune(s[i])) {
+				i++
+			}
+			tokens = append(tokens, s[start:i])
+		}
+	}
+
+	if len(tokens) > 0 {
+		return nil, fmt.Errorf("invalid EPD operation %s: missing ';'", tokens[0])
+	}
+	return ops, nil
+}
+
+// Operation returns the operands of the first operation with the opcode
+func (e *EPD) Operation(opcode string) ([]string, bool) {
+	for _, op := range e.Operations {
+		if op.Opcode == opcode {
+			return op.Operands, true
+		}
+	}
+	return nil, false
+}
+
+// Operand returns the first operand of the opcode, e.g. the id string
+func (e *EPD) Operand(opcode string) (string, bool) {
+	operands, ok := e.Operation(opcode)
+	if !ok || len(operands) == 0 {
+		return "", false
+	}
+	return operands[0], true
+}
+
+// SetOperation adds the operation or replaces the operands of an existing one
+func (e *EPD) SetOperation(opcode string, operands ...string) {
+	for i, op := range e.Operations {
+		if op.Opcode == opcode {
+			e.Operations[i].Operands = operands
+			return
+		}
+	}
+	e.Operations = append(e.Operations, EPDOperation{Opcode: opcode, Operands: operands})
+}
+
+// ID returns the id operation, or "" when the record has none
+func (e *EPD) ID() string {
+	id, _ := e.Operand("id")
+	return id
+}
+
+// BestMoves returns the moves of the bm operation
+func (e *EPD) BestMoves() ([]Move, error) {
+	return e.moves("bm")
+}
+
+// AvoidMoves returns the moves of the am operation
+func (e *EPD) AvoidMoves() ([]Move, error) {
+	return e.moves("am")
+}
+
+// moves parses the SAN operands of a move-list opcode
+func (e *EPD) moves(opcode string) ([]Move, error) {
+	operands, _ := e.Operation(opcode)
+	moves := make([]Move, 0, len(operands))
+	for _, san := range operands {
+		m, err := e.Game.ParseSAN(san)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EPD operation %s %s: %w", opcode, san, err)
+		}
+		moves = append(moves, m)
+	}
+	return moves, nil
+}
+
+// String writes the record back as EPD. The clocks are only written
+// through the hmc and fmvn operations.
+func (e *EPD) String() string {
+	fields := strings.Fields(e.Game.FEN())
+	var sb strings.Builder
+	sb.WriteString(strings.Join(fields[:4], " "))
+
+	for _, op := range e.Operations {
+		sb.WriteString(" " + op.Opcode)
+		for _, operand := range op.Operands {
+			sb.WriteString(" " + epdOperand(op.Opcode, operand))
+		}
+		sb.WriteString(";")
+	}
+	return sb.String()
+}
+
+// epdOperand quotes the string operands of id and the c0-c9 comments, and
+// any operand that would not read back as a single token
+func epdOperand(opcode, s string) string {
+	isString := opcode == "id" || (len(opcode) == 2 && opcode[0] == 'c' && opcode[1] >= '0' && opcode[1] <= '9')
+	if isString || s == "" || strings.ContainsAny(s, " \t;\"") {
+		return `"` + strings.ReplaceAll(s, `"`, "'") + `"`
+	}
+	return s
+}
+
+// ReadEPD reads one record per line, skipping blank lines and lines
+// starting with '#'. Errors give the line number.
+func ReadEPD(r io.Reader) ([]*EPD, error) {
+	var records []*EPD
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		e, err := ParseEPD(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		records = append(records, e)
+	}
+	return records, scanner.Err()
+}
+
+// EPDResult is the engine's answer to one test position
+type EPDResult struct {
+	ID     string
+	Move   Move
+	SAN    string
+	Passed bool
+	Err    error // Set when the record's moves could not be read or the engine found no move
+}
+
+// RunEPDSuite searches every position to depth with GetBestMove and scores
+// the answer: it passes if it is one of the bm moves (when given) and none
+// of the am moves. It returns the results in order and the number passed.
+func RunEPDSuite(suite []*EPD, depth int) ([]EPDResult, int) {
+	results := make([]EPDResult, len(suite))
+	passed := 0
+
+	for i, e := range suite {
+		res := &results[i]
+		res.ID = e.ID()
+
+		best, err := e.BestMoves()
+		if err != nil {
+			res.Err = err
+			continue
+		}
+		avoid, err := e.AvoidMoves()
+		if err != nil {
+			res.Err = err
+			continue
+		}
+		if len(best) == 0 && len(avoid) == 0 {
+			res.Err = fmt.Errorf("no bm or am operation")
+			continue
+		}
+
+		g := e.Game.Clone()
+		res.Move, res.Err = g.GetBestMove(depth)
+		if res.Err != nil {
+			continue
+		}
+		res.SAN = e.Game.MoveToSAN(res.Move)
+
+		res.Passed = (len(best) == 0 || containsMove(best, res.Move)) && !containsMove(avoid, res.Move)
+		if res.Passed {
+			passed++
+		}
+	}
+
+	return results, passed
+}
+
+func containsMove(moves []Move, m Move) bool {
+	for _, c := range moves {
+		if sameMove(c, m) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test reading and writing EPD records with their operations
+func TestParseEPD(t *testing.T) {
+	line := `2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - bm Qg6; id "WAC.001";`
+	e, err := ParseEPD(line)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if e.ID() != "WAC.001" {
+		t.Errorf("Expected id WAC.001, got %q", e.ID())
+	}
+	best, err := e.BestMoves()
+	if err != nil || len(best) != 1 || e.Game.MoveToSAN(best[0]) != "Qg6" {
+		t.Errorf("Expected bm Qg6, got %v (%v)", best, err)
+	}
+	if e.String() != line {
+		t.Errorf("Expected %s, got %s", line, e.String())
+	}
+
+	// Quoted operands keep spaces and semicolons; clocks come from hmc/fmvn
+	e, err = ParseEPD(`4k3/8/8/8/8/8/4P3/4K3 w - - c0 "Push; then promote"; am Kd1 Kf1; hmc 7; fmvn 42;`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c0, _ := e.Operand("c0"); c0 != "Push; then promote" {
+		t.Errorf("Expected c0 with semicolon, got %q", c0)
+	}
+	if avoid, _ := e.AvoidMoves(); len(avoid) != 2 {
+		t.Errorf("Expected two am moves, got %v", avoid)
+	}
+	if want := "4k3/8/8/8/8/8/4P3/4K3 w - - 7 42"; e.Game.FEN() != want {
+		t.Errorf("Expected %s, got %s", want, e.Game.FEN())
+	}
+	e.SetOperation("id", "pawn ending")
+	if want := `c0 "Push; then promote"; am Kd1 Kf1; hmc 7; fmvn 42; id "pawn ending";`; !strings.HasSuffix(e.String(), want) {
+		t.Errorf("Expected operations %s, got %s", want, e.String())
+	}
+
+	for _, bad := range []string{
+		"4k3/8/8/8/8/8/4P3/4K3 w -",
+		"4k3/8/8/8/8/8/4P3/4K3 w - - bm e4",
+		`4k3/8/8/8/8/8/4P3/4K3 w - - c0 "open;`,
+		"4k3/8/8/8/8/8/4P3/4K3 w - - hmc x;",
+		"4k3/8/8/8/8/8/4P3/4K3 w KQ - id x;",
+	} {
+		if _, err := ParseEPD(bad); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+// Test scoring engine answers against bm and am
+func TestRunEPDSuite(t *testing.T) {
+	suite, err := ReadEPD(strings.NewReader(`# Black's only move is Kg8
+7k/8/6K1/8/8/8/8/R7 b - - bm Kg8; id "forced";
+7k/8/6K1/8/8/8/8/R7 b - - am Kg8; id "avoid";
+
+7k/8/6K1/8/8/8/8/R7 b - - bm Kh7; id "illegal";
+`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	results, passed := RunEPDSuite(suite, 1)
+	if passed != 1 || len(results) != 3 {
+		t.Fatalf("Expected 1 of 3 passed, got %d of %d", passed, len(results))
+	}
+	if !results[0].Passed || results[0].SAN != "Kg8" {
+		t.Errorf("Expected forced Kg8 to pass, got %+v", results[0])
+	}
+	if results[1].Passed || results[1].Err != nil {
+		t.Errorf("Expected am Kg8 to fail, got %+v", results[1])
+	}
+	if results[2].Err == nil {
+		t.Error("Expected an error for an illegal bm move")
+	}
+
+	if _, err := ReadEPD(strings.NewReader("\n8/8/8/8 w - - id x;\n")); err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("Expected error on line 2, got %v", err)
+	}
+}
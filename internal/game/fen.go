@@ -1,76 +1,477 @@
 package game
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 	"unicode"
 )
 
-// LoadFEN parses a FEN string and updates the Game state
-func (g *Game) LoadFEN(fen string) {
-	parts := strings.Split(fen, " ")
+// FENError describes why a FEN string was rejected
+type FENError struct {
+	Field  string // Which part of the FEN is wrong, e.g. "piece placement"
+	Reason string
+}
+
+func (e *FENError) Error() string {
+	return fmt.Sprintf("invalid FEN %s: %s", e.Field, e.Reason)
+}
+
+func fenError(field, format string, args ...interface{}) error {
+	return &FENError{Field: field, Reason: fmt.Sprintf(format, args...)}
+}
 
-	// 1. Piece Placement
-	piecePlacement := parts[0]
-	g.Board = Board{} // Clear board
+// LoadFEN parses a FEN string and updates the Game state. The halfmove clock
+// and fullmove number may be omitted. Variants may add checks given ("+1+0")
+// or pockets ("RNBQKBNR[Nnp]"). The position is validated before the game is
+// touched, so on error the game is left unchanged.
+func (g *Game) LoadFEN(fen string) error {
+	parts := strings.Fields(fen)
+	maxFields := 6
+	if g.rules().CountsChecks() {
+		maxFields = 7
+	}
+	if len(parts) < 4 || len(parts) > maxFields {
+		return fenError("string", "expected 4 to %d space-separated fields, got %d", maxFields, len(parts))
+	}
+
+	// 1. Piece Placement, and the pockets in variants with drops
+	placement := parts[0]
+	drops := g.rules().HasPockets()
+	var pockets [2][7]int
+	if drops {
+		var pocket string
+		var err error
+		if placement, pocket, err = splitPocket(placement); err != nil {
+			return err
+		}
+		if pockets, err = parsePocket(pocket); err != nil {
+			return err
+		}
+	}
+	board, promoted, err := parsePlacement(placement, g.rules())
+	if err != nil {
+		return err
+	}
+
+	// 2. Turn
+	var turn Color
+	switch parts[1] {
+	case "w":
+		turn = White
+	case "b":
+		turn = Black
+	default:
+		return fenError("side to move", "expected 'w' or 'b', got %q", parts[1])
+	}
+
+	// 3. Castling Rights
+	castling, castlingRooks, chess960, err := parseCastling(parts[2], &board, g.Chess960)
+	if err != nil {
+		return err
+	}
+	if parts[2] != "-" && !g.rules().RoyalKing() {
+		return fenError("castling rights", "nobody castles in %s, expected '-'", g.rules().Name())
+	}
+
+	// 4. En Passant
+	enPassant, err := parseEnPassant(parts[3], &board, turn)
+	if err != nil {
+		return err
+	}
+
+	// 5. Halfmove Clock
+	halfmove := 0
+	if len(parts) > 4 {
+		n, err := strconv.Atoi(parts[4])
+		if err != nil || n < 0 {
+			return fenError("halfmove clock", "expected a non-negative integer, got %q", parts[4])
+		}
+		halfmove = n
+	}
+
+	// 6. Fullmove Number
+	fullmove := 1
+	if len(parts) > 5 {
+		n, err := strconv.Atoi(parts[5])
+		if err != nil || n < 1 {
+			return fenError("fullmove number", "expected a positive integer, got %q", parts[5])
+		}
+		fullmove = n
+	}
+
+	// 7. Checks given
+	var checks [2]int
+	if len(parts) > 6 {
+		if checks, err = parseChecks(parts[6]); err != nil {
+			return err
+		}
+	}
+
+	// The side that just moved cannot have left its king in check
+	bb := board.bitboards()
+	if checked(&bb, turn.Opponent(), g.rules()) {
+		return fenError("position", "%s is in check but it is %s's turn", turn.Opponent(), turn)
+	}
 
-	rank := 7
-	file := 0
+	g.Board = board
+	g.bb = bb
+	g.Turn = turn
+	g.Castling = castling
+	g.castlingRooks = castlingRooks
+	g.Chess960 = chess960
+	g.EnPassantTarget = enPassant
+	g.HalfmoveClock = halfmove
+	g.FullmoveNumber = fullmove
+	g.Checks = checks
+	g.Pockets = pockets
+	g.promoted = promoted
 
-	for _, char := range piecePlacement {
-		if char == '/' {
-			rank--
-			file = 0
+	// The game starts over from the loaded position
+	g.History = g.History[:0]
+	g.StateHistory = g.StateHistory[:0]
+	g.MoveResults = nil
+	g.hash = g.computeHash()
+	g.positionHashes = []uint64{g.Hash()}
+	g.declared = Outcome{}
+	g.resetTree()
+
+	return nil
+}
+
+// parsePlacement reads the piece placement field and checks that the
+// resulting board could occur in a game under the variant's rules. With
+// drops, a '~' after a piece marks it as promoted, and the pieces are not
+// limited to the starting set since captured ones come back. Without a
+// royal king, a side may have any number of kings.
+func parsePlacement(placement string, v Variant) (Board, Bitboard, error) {
+	drops := v.HasPockets()
+	var board Board
+	var promoted Bitboard
+	const field = "piece placement"
+
+	ranks := strings.Split(placement, "/")
+	if len(ranks) != 8 {
+		return board, promoted, fenError(field, "expected 8 ranks, got %d", len(ranks))
+	}
+
+	for i, rankStr := range ranks {
+		rank := 7 - i
+		file := 0
+		lastWasDigit := false
+
+		for _, char := range rankStr {
+			if unicode.IsDigit(char) {
+				if char < '1' || char > '8' {
+					return board, promoted, fenError(field, "rank %d has invalid empty-square count '%c'", rank+1, char)
+				}
+				if lastWasDigit {
+					return board, promoted, fenError(field, "rank %d has consecutive empty-square counts", rank+1)
+				}
+				file += int(char - '0')
+				lastWasDigit = true
+			} else if char == '~' && drops && file > 0 && !lastWasDigit {
+				promoted |= squareBB(rank*8 + file - 1)
+			} else {
+				piece := charToPiece(char)
+				if piece.Type == Empty {
+					return board, promoted, fenError(field, "rank %d has invalid piece '%c'", rank+1, char)
+				}
+				if file < 8 {
+					board[rank*8+file] = piece
+				}
+				file++
+				lastWasDigit = false
+			}
+
+			if file > 8 {
+				return board, promoted, fenError(field, "rank %d has more than 8 files", rank+1)
+			}
+		}
+
+		if file != 8 {
+			return board, promoted, fenError(field, "rank %d has %d files, expected 8", rank+1, file)
+		}
+	}
+
+	// Material sanity checks
+	if pc, ok := v.(placementChecker); ok {
+		return board, promoted, pc.checkPlacement(&board)
+	}
+	var kings, pawns, pieces [2]int
+	for sq, p := range board {
+		if p.Type == Empty {
 			continue
 		}
+		pieces[p.Color]++
+		switch p.Type {
+		case King:
+			kings[p.Color]++
+		case Pawn:
+			pawns[p.Color]++
+			if sq/8 == 0 || sq/8 == 7 {
+				return board, promoted, fenError(field, "%s pawn on the back rank at %s", p.Color, IndexToCoord(sq))
+			}
+		}
+	}
 
-		if unicode.IsDigit(char) {
-			emptyCount := int(char - '0')
-			file += emptyCount
-		} else {
-			index := rank*8 + file
-			g.Board[index] = charToPiece(char)
-			file++
+	for _, c := range []Color{White, Black} {
+		if kings[c] != 1 && v.RoyalKing() {
+			return board, promoted, fenError(field, "%s must have exactly one king, found %d", c, kings[c])
+		}
+		if drops {
+			continue
+		}
+		if pawns[c] > 8 {
+			return board, promoted, fenError(field, "%s has %d pawns, at most 8 allowed", c, pawns[c])
+		}
+		if pieces[c] > 16 {
+			return board, promoted, fenError(field, "%s has %d pieces, at most 16 allowed", c, pieces[c])
 		}
 	}
 
-	// 2. Turn
-	if len(parts) > 1 {
-		if parts[1] == "w" {
-			g.Turn = White
-		} else {
-			g.Turn = Black
+	return board, promoted, nil
+}
+
+// placementChecker is implemented by variants whose armies break the
+// standard material limits, e.g. Horde. checkPlacement then replaces the
+// standard checks.
+type placementChecker interface {
+	checkPlacement(b *Board) error
+}
+
+// parseCastling reads the castling field, rejecting rights whose king or rook
+// is no longer on its original square. Besides KQkq it accepts the rook file
+// letters of Shredder-FEN and X-FEN (e.g. "HAha"), which name the castling
+// rook in Chess960; using them makes the game a Chess960 game. In Chess960,
+// K and Q stand for the outermost rook on that side of the king. It returns
+// the rights, the castling rook squares and whether the game follows
+// Chess960 rules.
+func parseCastling(field string, board *Board, chess960 bool) (CastlingRights, [2][2]int, bool, error) {
+	var rights CastlingRights
+	rooks := standardCastlingRooks
+	const name = "castling rights"
+
+	if field == "-" {
+		return rights, rooks, chess960, nil
+	}
+
+	// File letters can only be read once the king is known, so note them first
+	for _, char := range field {
+		if lower := unicode.ToLower(char); lower >= 'a' && lower <= 'h' {
+			chess960 = true
 		}
 	}
 
-	// 3. Castling Rights
-	// Default to false, then enable based on string
-	g.Castling = CastlingRights{}
-	if len(parts) > 2 {
-		c := parts[2]
-		if c != "-" {
-			for _, char := range c {
-				switch char {
-				case 'K':
-					g.Castling.WhiteKingSide = true
-				case 'Q':
-					g.Castling.WhiteQueenSide = true
-				case 'k':
-					g.Castling.BlackKingSide = true
-				case 'q':
-					g.Castling.BlackQueenSide = true
+	for _, char := range field {
+		color := White
+		if unicode.IsLower(char) {
+			color = Black
+		}
+		backRank := 0
+		if color == Black {
+			backRank = 56
+		}
+
+		king := board.kingSquare(color)
+		if king&^7 != backRank {
+			king = -1
+		}
+
+		var kingSide bool
+		rook := -1
+		switch lower := unicode.ToLower(char); {
+		case lower == 'k' || lower == 'q':
+			kingSide = lower == 'k'
+			if !chess960 {
+				// Normal chess: the king and rook must not have moved
+				king, rook = backRank+4, standardCastlingRooks[color][castlingSideIndex(kingSide)]
+				if board[king] != (Piece{Type: King, Color: color}) {
+					return rights, rooks, false, fenError(name, "'%c' requires the %s king on %s", char, color, IndexToCoord(king))
 				}
+				if board[rook] != (Piece{Type: Rook, Color: color}) {
+					return rights, rooks, false, fenError(name, "'%c' requires a %s rook on %s", char, color, IndexToCoord(rook))
+				}
+				break
+			}
+			if king < 0 {
+				return rights, rooks, false, fenError(name, "'%c' requires the %s king on its back rank", char, color)
+			}
+			if rook = outermostRook(board, color, king, kingSide); rook < 0 {
+				return rights, rooks, false, fenError(name, "'%c' requires a %s rook on the %s of the king", char, color, castlingSideName(kingSide))
+			}
+		case lower >= 'a' && lower <= 'h':
+			rook = backRank + int(lower-'a')
+			if board[rook] != (Piece{Type: Rook, Color: color}) {
+				return rights, rooks, false, fenError(name, "'%c' requires a %s rook on %s", char, color, IndexToCoord(rook))
 			}
+			if king < 0 {
+				return rights, rooks, false, fenError(name, "'%c' requires the %s king on its back rank", char, color)
+			}
+			kingSide = rook > king
+		default:
+			return rights, rooks, false, fenError(name, "invalid character '%c'", char)
+		}
+
+		flag := rights.right(color, kingSide)
+		if *flag {
+			return rights, rooks, false, fenError(name, "'%c' repeats the %s %s right", char, color, castlingSideName(kingSide))
 		}
+		*flag = true
+		rooks[color][castlingSideIndex(kingSide)] = rook
 	}
 
+	return rights, rooks, chess960, nil
+}
+
+// parseEnPassant reads the en passant field and checks that a pawn could
+// just have made a double step past that square
+func parseEnPassant(field string, board *Board, turn Color) (int, error) {
+	const name = "en passant square"
+
+	if field == "-" {
+		return -1, nil
+	}
+
+	sq := CoordToIndex(field)
+	if sq < 0 || sq > 63 || field[0] < 'a' || field[0] > 'h' || field[1] < '1' || field[1] > '8' {
+		return -1, fenError(name, "%q is not a square", field)
+	}
+
+	// White to move means Black just pushed, so the target is on rank 6
+	expectedRank, pawnSq, originSq := 5, sq-8, sq+8
+	mover := Black
+	if turn == Black {
+		expectedRank, pawnSq, originSq = 2, sq+8, sq-8
+		mover = White
+	}
+
+	if sq/8 != expectedRank {
+		return -1, fenError(name, "%s is not on rank %d", field, expectedRank+1)
+	}
+	if board[sq].Type != Empty || board[originSq].Type != Empty {
+		return -1, fenError(name, "%s and %s must be empty after a double pawn push", field, IndexToCoord(originSq))
+	}
+	if board[pawnSq] != (Piece{Type: Pawn, Color: mover}) {
+		return -1, fenError(name, "no %s pawn on %s that could have just moved", mover, IndexToCoord(pawnSq))
+	}
+
+	return sq, nil
+}
+
+// parseChecks reads the checks given by White and Black, written "+W+B"
+func parseChecks(field string) ([2]int, error) {
+	var checks [2]int
+	parts := strings.Split(field, "+")
+	if len(parts) != 3 || parts[0] != "" {
+		return checks, fenError("checks", "expected +W+B, got %q", field)
+	}
+	for i, part := range parts[1:] {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return checks, fenError("checks", "expected +W+B, got %q", field)
+		}
+		if n > ThreeCheckLimit {
+			return checks, fenError("checks", "%d checks is more than the %d that win", n, ThreeCheckLimit)
+		}
+		checks[i] = n
+	}
+	return checks, nil
+}
+
+// FEN serializes the current position as a six-field FEN string, plus the
+// checks or pockets of variants that have them. LoadFEN(g.FEN()) restores
+// exactly the same position.
+func (g *Game) FEN() string {
+	var sb strings.Builder
+
+	// 1. Piece Placement (rank 8 first)
+	for rank := 7; rank >= 0; rank-- {
+		empty := 0
+		for file := 0; file < 8; file++ {
+			piece := g.Board[rank*8+file]
+			if piece.Type == Empty {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+			sb.WriteString(piece.String())
+			if g.promoted.Has(rank*8 + file) {
+				sb.WriteByte('~')
+			}
+		}
+		if empty > 0 {
+			sb.WriteString(strconv.Itoa(empty))
+		}
+		if rank > 0 {
+			sb.WriteByte('/')
+		}
+	}
+	if g.rules().HasPockets() {
+		sb.WriteString("[" + g.pocketField() + "]")
+	}
+
+	// 2. Turn
+	if g.Turn == White {
+		sb.WriteString(" w ")
+	} else {
+		sb.WriteString(" b ")
+	}
+
+	// 3. Castling Rights
+	sb.WriteString(g.castlingField())
+
 	// 4. En Passant
-	g.EnPassantTarget = -1
-	if len(parts) > 3 {
-		ep := parts[3]
-		if ep != "-" {
-			g.EnPassantTarget = CoordToIndex(ep)
+	if g.EnPassantTarget >= 0 {
+		sb.WriteString(" " + IndexToCoord(g.EnPassantTarget))
+	} else {
+		sb.WriteString(" -")
+	}
+
+	// 5 & 6. Clocks
+	sb.WriteString(fmt.Sprintf(" %d %d", g.HalfmoveClock, g.FullmoveNumber))
+
+	// 7. Checks given, for variants that count them
+	if g.rules().CountsChecks() {
+		sb.WriteString(fmt.Sprintf(" +%d+%d", g.Checks[White], g.Checks[Black]))
+	}
+
+	return sb.String()
+}
+
+// castlingField writes the castling rights as KQkq. In Chess960 it follows
+// X-FEN: a right whose rook is not the outermost one on its side is written
+// as the rook's file instead, e.g. "Gk".
+func (g *Game) castlingField() string {
+	var sb strings.Builder
+	for _, c := range []Color{White, Black} {
+		for _, kingSide := range []bool{true, false} {
+			if !*g.Castling.right(c, kingSide) {
+				continue
+			}
+			char := 'Q'
+			if kingSide {
+				char = 'K'
+			}
+			if g.Chess960 {
+				rook := g.castlingRook(c, kingSide)
+				if king := g.Board.kingSquare(c); king < 0 || outermostRook(&g.Board, c, king, kingSide) != rook {
+					char = 'A' + rune(rook%8)
+				}
+			}
+			if c == Black {
+				char = unicode.ToLower(char)
+			}
+			sb.WriteRune(char)
 		}
 	}
+	if sb.Len() == 0 {
+		return "-"
+	}
+	return sb.String()
 }
 
 func charToPiece(char rune) Piece {
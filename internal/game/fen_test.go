@@ -0,0 +1,133 @@
+package game
+
+import (
+	"testing"
+)
+
+// fenCorpus covers start positions, castling subsets, en passant squares,
+// promotions-in-waiting and large move clocks
+var fenCorpus = []string{
+	StartFEN,
+	"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+	"rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq c6 0 2",
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+	"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+	"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+	"4k3/8/8/8/8/8/8/4K2R w K - 99 120",
+	"r3k3/8/8/8/8/8/8/4K3 b q - 0 75",
+	"8/8/8/8/8/8/8/K6k w - - 0 1",
+}
+
+// Test that every corpus FEN serializes back to itself
+func TestFENRoundTrip(t *testing.T) {
+	for _, fen := range fenCorpus {
+		g := NewGame()
+		g.LoadFEN(fen)
+		if got := g.FEN(); got != fen {
+			t.Errorf("FEN round trip failed:\n  loaded %s\n  got    %s", fen, got)
+		}
+	}
+}
+
+// Test that positions reached by play survive a FEN round trip unchanged
+func TestFENRoundTripAfterMoves(t *testing.T) {
+	g := NewGame()
+	moves := []Move{
+		{From: 12, To: 28, Piece: Pawn},   // e4
+		{From: 50, To: 34, Piece: Pawn},   // c5
+		{From: 6, To: 21, Piece: Knight},  // Nf3
+		{From: 57, To: 42, Piece: Knight}, // Nc6
+		{From: 5, To: 33, Piece: Bishop},  // Bb5
+		{From: 51, To: 43, Piece: Pawn},   // d6
+		{From: 4, To: 6, Piece: King, MoveType: MoveCastling},
+	}
+
+	for _, m := range moves {
+		g.MakeMove(m)
+
+		loaded := NewGame()
+		loaded.LoadFEN(g.FEN())
+
+		if loaded.Board != g.Board || loaded.Turn != g.Turn || loaded.Castling != g.Castling ||
+			loaded.EnPassantTarget != g.EnPassantTarget || loaded.HalfmoveClock != g.HalfmoveClock ||
+			loaded.FullmoveNumber != g.FullmoveNumber {
+			t.Errorf("Position after %s did not round trip: %s", IndexToCoord(m.To), g.FEN())
+		}
+	}
+
+	if want := "r1bqkbnr/pp2pppp/2np4/1Bp5/4P3/5N2/PPPP1PPP/RNBQ1RK1 b kq - 1 4"; g.FEN() != want {
+		t.Errorf("Expected %s, got %s", want, g.FEN())
+	}
+}
+
+// Test that malformed or impossible FENs are rejected with the offending field
+func TestLoadFENValidation(t *testing.T) {
+	tests := []struct {
+		fen   string
+		field string
+	}{
+		{"", "string"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 extra", "string"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP w KQkq - 0 1", "piece placement"},
+		{"rnbqkbnr/pppppppp/9/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "piece placement"},
+		{"rnbqkbnrr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "piece placement"},
+		{"rnbqkbnr/ppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "piece placement"},
+		{"rnbqkbnr/pppppppp/44/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "piece placement"},
+		{"rnbqkbnr/ppppxppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "piece placement"},
+		{"rnbq1bnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQ - 0 1", "piece placement"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKKNR w KQkq - 0 1", "piece placement"},
+		{"Pnbqkbnr/pppppppp/8/8/8/8/1PPPPPPP/RNBQKBNR w KQkq - 0 1", "piece placement"},
+		{"rnbqkbnr/pppppppp/8/8/8/P7/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "piece placement"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1", "side to move"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkx - 0 1", "castling rights"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KKkq - 0 1", "castling rights"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBN1 w KQkq - 0 1", "castling rights"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQ1BNR w KQkq - 0 1", "piece placement"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq e9 0 1", "en passant square"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq e3 0 1", "en passant square"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq e6 0 1", "en passant square"},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq d3 0 1", "en passant square"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - -1 1", "halfmove clock"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 0", "fullmove number"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - x 1", "halfmove clock"},
+		{"4k3/8/8/8/8/8/8/4K2R w - - 0 1", ""},
+		{"4k2R/8/8/8/8/8/8/4K3 w - - 0 1", "position"},
+	}
+
+	for _, tt := range tests {
+		g := NewGame()
+		err := g.LoadFEN(tt.fen)
+
+		if tt.field == "" {
+			if err != nil {
+				t.Errorf("%q: unexpected error %v", tt.fen, err)
+			}
+			continue
+		}
+
+		fenErr, ok := err.(*FENError)
+		if !ok {
+			t.Errorf("%q: expected *FENError for %s, got %v", tt.fen, tt.field, err)
+			continue
+		}
+		if fenErr.Field != tt.field {
+			t.Errorf("%q: expected error in %s, got %v", tt.fen, tt.field, err)
+		}
+		if g.FEN() != StartFEN {
+			t.Errorf("%q: game should be unchanged after a rejected FEN", tt.fen)
+		}
+	}
+}
+
+// Test that omitted move clocks default to 0 and 1
+func TestLoadFENWithoutClocks(t *testing.T) {
+	g := NewGame()
+	if err := g.LoadFEN("4k3/8/8/8/8/8/8/4K3 b - -"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if g.HalfmoveClock != 0 || g.FullmoveNumber != 1 {
+		t.Errorf("Expected default clocks 0/1, got %d/%d", g.HalfmoveClock, g.FullmoveNumber)
+	}
+}
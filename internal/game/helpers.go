@@ -24,3 +24,14 @@ func CoordToIndex(coord string) int {
 func IsOnBoard(sq int) bool {
 	return sq >= 0 && sq < 64
 }
+
+// sameMove compares moves by squares and promotion piece, ignoring the
+// piece and move type fields that hand-built moves may leave unset. Castling
+// still counts, since in Chess960 the king may castle onto a square it
+// could also step to, and drops are told apart by the piece dropped.
+func sameMove(a, b Move) bool {
+	return a.From == b.From && a.To == b.To && a.Promotion == b.Promotion &&
+		(a.MoveType == MoveCastling) == (b.MoveType == MoveCastling) &&
+		(a.MoveType == MoveDrop) == (b.MoveType == MoveDrop) &&
+		(a.MoveType != MoveDrop || a.Piece == b.Piece)
+}
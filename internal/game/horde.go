@@ -0,0 +1,64 @@
+package game
+
+// Horde pits 36 White pawns, without a king, against the normal Black
+// army. Pawns on the first rank may step two squares. White wins by
+// checkmate, Black by capturing every White piece.
+type Horde struct{ StandardRules }
+
+func (Horde) Name() string { return "Horde" }
+func (Horde) StartFEN() string {
+	return "rnbqkbnr/pppppppp/8/1PP2PP1/PPPPPPPP/PPPPPPPP/PPPPPPPP/PPPPPPPP w kq - 0 1"
+}
+
+// InsufficientMaterial is never true: Black can always try to capture the
+// rest of the horde
+func (Horde) InsufficientMaterial(g *Game) bool { return false }
+
+// CanWin is always true: Black can capture the horde, and the horde can
+// promote and mate
+func (Horde) CanWin(g *Game, c Color) bool { return true }
+
+func (Horde) Outcome(g *Game) Outcome {
+	for _, p := range g.Board {
+		if p.Type != Empty && p.Color == White {
+			return Outcome{}
+		}
+	}
+	return Outcome{Result: BlackWins, Termination: TerminationHordeCaptured}
+}
+
+// checkPlacement allows White any number of pawns and pieces up to the 36
+// the horde starts with, pawns on the first rank and no king
+func (Horde) checkPlacement(b *Board) error {
+	const field = "piece placement"
+	var kings, pawns, pieces [2]int
+	for sq, p := range b {
+		if p.Type == Empty {
+			continue
+		}
+		pieces[p.Color]++
+		switch p.Type {
+		case King:
+			kings[p.Color]++
+		case Pawn:
+			pawns[p.Color]++
+			if sq/8 == 7 || (sq/8 == 0 && p.Color == Black) {
+				return fenError(field, "%s pawn on the back rank at %s", p.Color, IndexToCoord(sq))
+			}
+		}
+	}
+
+	switch {
+	case kings[White] != 0:
+		return fenError(field, "the White horde has no king, found %d", kings[White])
+	case pieces[White] > 36:
+		return fenError(field, "White has %d pieces, at most 36 allowed", pieces[White])
+	case kings[Black] != 1:
+		return fenError(field, "Black must have exactly one king, found %d", kings[Black])
+	case pawns[Black] > 8:
+		return fenError(field, "Black has %d pawns, at most 8 allowed", pawns[Black])
+	case pieces[Black] > 16:
+		return fenError(field, "Black has %d pieces, at most 16 allowed", pieces[Black])
+	}
+	return nil
+}
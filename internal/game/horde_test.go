@@ -0,0 +1,65 @@
+package game
+
+import "testing"
+
+func TestHordePerft(t *testing.T) {
+	tests := []struct {
+		fen    string
+		counts []uint64
+	}{
+		{Horde{}.StartFEN(), []uint64{8, 128, 1274, 23310}},
+		{"4k3/pp4q1/3P2p1/8/P3PP2/PPP2r2/PPP5/PPPP4 b - - 0 1", []uint64{30, 241, 6633, 56539}},
+		{"k7/5p2/4p2P/3p2P1/2p2P2/1p2P2P/p2P2P1/2P2P2 w - - 0 1", []uint64{13, 172, 2205, 33781}},
+	}
+	for _, tt := range tests {
+		g := NewVariantGame(Horde{})
+		if err := g.LoadFEN(tt.fen); err != nil {
+			t.Fatal(err)
+		}
+		for i, want := range tt.counts {
+			if testing.Short() && want > 10000 {
+				break
+			}
+			if got := g.Perft(i + 1); got != want {
+				t.Errorf("%s: perft(%d) = %d, want %d", tt.fen, i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestHordeRules(t *testing.T) {
+	g := NewVariantGame(Horde{})
+
+	// Pawns on the first rank may double-step, but not be taken en passant
+	if err := g.LoadFEN("4k3/8/8/8/8/8/1p6/P7 w - - 0 1"); err != nil {
+		t.Fatal(err)
+	}
+	m, err := g.ParseMoveInput("a1a3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.MakeMove(m)
+	if g.EnPassantTarget != -1 {
+		t.Errorf("En passant square = %s, want none", IndexToCoord(g.EnPassantTarget))
+	}
+
+	// Capturing the last White piece wins for Black
+	if err := g.LoadFEN("4k3/8/8/8/8/8/1p6/P7 b - - 0 1"); err != nil {
+		t.Fatal(err)
+	}
+	if o := g.Outcome(); o.IsOver() {
+		t.Fatalf("The game is not over yet: %v", o)
+	}
+	m, err = g.ParseSAN("bxa1=Q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.MakeMove(m)
+	if o := g.Outcome(); o.Result != BlackWins || o.Termination != TerminationHordeCaptured {
+		t.Errorf("Outcome = %v, want Black to win by capturing the horde", o)
+	}
+
+	if err := g.LoadFEN("4k3/8/8/8/8/8/8/P3K3 w - - 0 1"); err == nil {
+		t.Error("The horde has no king")
+	}
+}
@@ -25,7 +25,7 @@ func ParseMove(input string, legalMoves []Move) (Move, error) {
 		return Move{}, fmt.Errorf("invalid coordinates")
 	}
 
-	// --- 1. Standard Move Matching ---
+	// Collect every legal move between the two squares
 	var candidates []Move
 	for _, m := range legalMoves {
 		if m.From == fromIdx && m.To == toIdx {
@@ -33,14 +33,8 @@ func ParseMove(input string, legalMoves []Move) (Move, error) {
 		}
 	}
 
-	// --- 2. Special Castling Handling (King -> Rook) ---
-	// If no standard move found, check if user tried to castle by clicking King then Rook
 	if len(candidates) == 0 {
-		candidates = checkAlternativeCastling(fromIdx, toIdx, legalMoves)
-	}
-
-	if len(candidates) == 0 {
-		return Move{}, fmt.Errorf("illegal move")
+		return Move{}, ErrIllegalMove
 	}
 
 	// If only one candidate, it's a standard move (or unique move)
@@ -48,10 +42,11 @@ func ParseMove(input string, legalMoves []Move) (Move, error) {
 		return candidates[0], nil
 	}
 
-	// If multiple candidates, it must be a promotion (Q, R, B, N available)
-	// We need the 5th character to distinguish.
+	// If multiple candidates, it must be a promotion (Q, R, B, N available,
+	// and K where the king is an ordinary piece). We need the 5th character
+	// to distinguish.
 	if len(cleanInput) != 5 {
-		return Move{}, fmt.Errorf("promotion detected. Please specify piece (q, r, b, n). Example: %sq", cleanInput)
+		return Move{}, fmt.Errorf("promotion detected. Please specify piece (%s). Example: %sq", promotionLetters(candidates, ", "), cleanInput)
 	}
 
 	promotionChar := rune(cleanInput[4])
@@ -66,8 +61,10 @@ func ParseMove(input string, legalMoves []Move) (Move, error) {
 		targetType = Bishop
 	case "n":
 		targetType = Knight
+	case "k":
+		targetType = King
 	default:
-		return Move{}, fmt.Errorf("invalid promotion piece '%c'. Use q, r, b, or n", promotionChar)
+		return Move{}, fmt.Errorf("invalid promotion piece '%c'. Use %s", promotionChar, promotionLetters(candidates, " or "))
 	}
 
 	// Find the specific promotion move
@@ -80,30 +77,17 @@ func ParseMove(input string, legalMoves []Move) (Move, error) {
 	return Move{}, fmt.Errorf("could not match promotion move")
 }
 
-// checkAlternativeCastling allows castling by clicking King -> Rook (e.g. e1h1 -> e1g1)
-func checkAlternativeCastling(from, to int, legalMoves []Move) []Move {
-	// Map of King->Rook moves to their actual King->Dest moves
-	// Key: KingFrom * 100 + RookTo, Value: Actual KingTo
-	castlingMap := map[int]int{
-		407:  6,  // White Short (e1->h1 implies e1->g1)
-		400:  2,  // White Long  (e1->a1 implies e1->c1)
-		6063: 62, // Black Short (e8->h8 implies e8->g8)
-		6056: 58, // Black Long  (e8->a8 implies e8->c8)
-	}
-
-	key := from*100 + to
-	targetSq, exists := castlingMap[key]
-
-	if !exists {
-		return nil
-	}
-
-	// Check if the actual castling move exists in legal moves
-	var result []Move
-	for _, m := range legalMoves {
-		if m.From == from && m.To == targetSq && m.MoveType == MoveCastling {
-			result = append(result, m)
+// promotionLetters lists the lower case letters of the promotion choices,
+// e.g. "q, r, b, n", with the last one set off by sep
+func promotionLetters(candidates []Move, sep string) string {
+	var letters []string
+	for _, m := range candidates {
+		if m.Promotion != Empty {
+			letters = append(letters, strings.ToLower(Piece{Type: m.Promotion}.String()))
 		}
 	}
-	return result
+	if len(letters) < 2 {
+		return strings.Join(letters, "")
+	}
+	return strings.Join(letters[:len(letters)-1], ", ") + sep + letters[len(letters)-1]
 }
@@ -9,10 +9,11 @@ func TestKingCannotBeCaptured(t *testing.T) {
 	g := NewGame()
 
 	// Set up a scenario where a pawn could "capture" the king
-	g.Board = Board{}
-	g.Board[20] = Piece{Type: Pawn, Color: White} // e3
-	g.Board[11] = Piece{Type: King, Color: Black} // d2
-	g.Board[60] = Piece{Type: King, Color: White} // e1
+	var board Board
+	board[20] = Piece{Type: Pawn, Color: White} // e3
+	board[11] = Piece{Type: King, Color: Black} // d2
+	board[4] = Piece{Type: King, Color: White}  // e1
+	g.SetBoard(board)
 	g.Turn = White
 
 	legalMoves := g.GenerateLegalMoves()
@@ -30,13 +31,14 @@ func TestCastling(t *testing.T) {
 	g := NewGame()
 
 	// Set up castling position for white
-	g.Board = Board{}
-	g.Board[60] = Piece{Type: King, Color: White} // e1
-	g.Board[63] = Piece{Type: Rook, Color: White} // h1
-	g.Board[56] = Piece{Type: Rook, Color: White} // a1
-	g.Board[4] = Piece{Type: King, Color: Black}  // e8
-	g.Board[7] = Piece{Type: Rook, Color: Black}  // h8
-	g.Board[0] = Piece{Type: Rook, Color: Black}  // a8
+	var board Board
+	board[4] = Piece{Type: King, Color: White}  // e1
+	board[7] = Piece{Type: Rook, Color: White}  // h1
+	board[0] = Piece{Type: Rook, Color: White}  // a1
+	board[60] = Piece{Type: King, Color: Black} // e8
+	board[63] = Piece{Type: Rook, Color: Black} // h8
+	board[56] = Piece{Type: Rook, Color: Black} // a8
+	g.SetBoard(board)
 
 	g.Turn = White
 	g.Castling = CastlingRights{
@@ -53,10 +55,10 @@ func TestCastling(t *testing.T) {
 	foundQueenside := false
 
 	for _, m := range legalMoves {
-		if m.From == 60 && m.To == 62 && m.MoveType == MoveCastling {
+		if m.From == 4 && m.To == 6 && m.MoveType == MoveCastling {
 			foundKingside = true
 		}
-		if m.From == 60 && m.To == 58 && m.MoveType == MoveCastling {
+		if m.From == 4 && m.To == 2 && m.MoveType == MoveCastling {
 			foundQueenside = true
 		}
 	}
@@ -73,11 +75,12 @@ func TestCastling(t *testing.T) {
 func TestCannotCastleThroughCheck(t *testing.T) {
 	g := NewGame()
 
-	g.Board = Board{}
-	g.Board[60] = Piece{Type: King, Color: White} // e1
-	g.Board[63] = Piece{Type: Rook, Color: White} // h1
-	g.Board[13] = Piece{Type: Rook, Color: Black} // f2 - attacks f1
-	g.Board[4] = Piece{Type: King, Color: Black}  // e8
+	var board Board
+	board[4] = Piece{Type: King, Color: White}  // e1
+	board[7] = Piece{Type: Rook, Color: White}  // h1
+	board[13] = Piece{Type: Rook, Color: Black} // f2 - attacks f1
+	board[60] = Piece{Type: King, Color: Black} // e8
+	g.SetBoard(board)
 
 	g.Turn = White
 	g.Castling.WhiteKingSide = true
@@ -86,7 +89,7 @@ func TestCannotCastleThroughCheck(t *testing.T) {
 
 	// Should not be able to castle through f1
 	for _, m := range legalMoves {
-		if m.From == 60 && m.To == 62 && m.MoveType == MoveCastling {
+		if m.From == 4 && m.To == 6 && m.MoveType == MoveCastling {
 			t.Error("Should not be able to castle through check (f1 is attacked)")
 		}
 	}
@@ -96,11 +99,12 @@ func TestCannotCastleThroughCheck(t *testing.T) {
 func TestCannotCastleOutOfCheck(t *testing.T) {
 	g := NewGame()
 
-	g.Board = Board{}
-	g.Board[60] = Piece{Type: King, Color: White} // e1
-	g.Board[63] = Piece{Type: Rook, Color: White} // h1
-	g.Board[12] = Piece{Type: Rook, Color: Black} // e2 - checks king on e1
-	g.Board[4] = Piece{Type: King, Color: Black}  // e8
+	var board Board
+	board[4] = Piece{Type: King, Color: White}  // e1
+	board[7] = Piece{Type: Rook, Color: White}  // h1
+	board[12] = Piece{Type: Rook, Color: Black} // e2 - checks king on e1
+	board[60] = Piece{Type: King, Color: Black} // e8
+	g.SetBoard(board)
 
 	g.Turn = White
 	g.Castling.WhiteKingSide = true
@@ -120,20 +124,21 @@ func TestEnPassant(t *testing.T) {
 	g := NewGame()
 
 	// Set up en passant scenario
-	g.Board = Board{}
-	g.Board[35] = Piece{Type: Pawn, Color: White} // d5
-	g.Board[36] = Piece{Type: Pawn, Color: Black} // e5 (just moved from e7)
-	g.Board[60] = Piece{Type: King, Color: White} // e1
-	g.Board[4] = Piece{Type: King, Color: Black}  // e8
+	var board Board
+	board[35] = Piece{Type: Pawn, Color: White} // d5
+	board[36] = Piece{Type: Pawn, Color: Black} // e5 (just moved from e7)
+	board[4] = Piece{Type: King, Color: White}  // e1
+	board[60] = Piece{Type: King, Color: Black} // e8
+	g.SetBoard(board)
 
 	g.Turn = White
-	g.EnPassantTarget = 28 // e6 - the square behind the black pawn
+	g.EnPassantTarget = 44 // e6 - the square behind the black pawn
 
 	legalMoves := g.GenerateLegalMoves()
 
 	foundEnPassant := false
 	for _, m := range legalMoves {
-		if m.From == 35 && m.To == 28 && m.MoveType == MoveEnPassant {
+		if m.From == 35 && m.To == 44 && m.MoveType == MoveEnPassant {
 			foundEnPassant = true
 		}
 	}
@@ -144,7 +149,7 @@ func TestEnPassant(t *testing.T) {
 
 	// Execute the en passant move
 	for _, m := range legalMoves {
-		if m.From == 35 && m.To == 28 && m.MoveType == MoveEnPassant {
+		if m.From == 35 && m.To == 44 && m.MoveType == MoveEnPassant {
 			g.MakeMove(m)
 			break
 		}
@@ -156,19 +161,85 @@ func TestEnPassant(t *testing.T) {
 	}
 
 	// Check that the capturing pawn is in the right place
-	if g.Board[28].Type != Pawn || g.Board[28].Color != White {
+	if g.Board[44].Type != Pawn || g.Board[44].Color != White {
 		t.Error("White pawn should be on e6")
 	}
 }
 
+// Test en passant when both pawns shield the king along the rank, and when
+// the capture answers a check from the pawn
+func TestEnPassantPins(t *testing.T) {
+	tests := []struct {
+		fen   string
+		legal bool
+	}{
+		{"8/8/8/KPp4r/8/8/8/4k3 w - c6 0 1", false},
+		{"8/8/8/KPp5/8/8/8/4k2r w - c6 0 1", true},
+		{"8/8/8/2k5/3Pp3/8/8/4K3 b - d3 0 1", true},
+		{"8/8/8/8/k2Pp2Q/8/8/4K3 b - d3 0 1", false},
+	}
+	for _, tt := range tests {
+		g := NewGame()
+		if err := g.LoadFEN(tt.fen); err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, m := range g.GenerateLegalMoves() {
+			found = found || m.MoveType == MoveEnPassant
+		}
+		if found != tt.legal {
+			t.Errorf("%s: en passant legal = %v, want %v", tt.fen, found, tt.legal)
+		}
+	}
+}
+
+// Test that checking pins and checkers up front keeps the same moves as
+// playing every move out, across the perft positions and their replies
+func TestLegalMovesMatchPlayedOut(t *testing.T) {
+	var walk func(g *Game, depth int)
+	walk = func(g *Game, depth int) {
+		var want []Move
+		for _, m := range g.GeneratePseudoLegalMoves() {
+			if g.isMoveLegal(m) {
+				want = append(want, m)
+			}
+		}
+		got := g.GenerateLegalMoves()
+		if len(got) != len(want) {
+			t.Fatalf("%s: %d legal moves, want %d", g.FEN(), len(got), len(want))
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("%s: move %d is %v, want %v", g.FEN(), i, got[i], want[i])
+			}
+		}
+		if depth == 0 {
+			return
+		}
+		for _, m := range got {
+			snapshot := g.playMove(m)
+			walk(g, depth-1)
+			g.retractMove(snapshot)
+		}
+	}
+	for _, pos := range perftPositions {
+		g := NewGame()
+		if err := g.LoadFEN(pos.fen); err != nil {
+			t.Fatalf("%s: %v", pos.name, err)
+		}
+		walk(g, 2)
+	}
+}
+
 // Test pawn promotion
 func TestPawnPromotion(t *testing.T) {
 	g := NewGame()
 
-	g.Board = Board{}
-	g.Board[54] = Piece{Type: Pawn, Color: White} // g7
-	g.Board[60] = Piece{Type: King, Color: White} // e1
-	g.Board[4] = Piece{Type: King, Color: Black}  // e8
+	var board Board
+	board[54] = Piece{Type: Pawn, Color: White} // g7
+	board[4] = Piece{Type: King, Color: White}  // e1
+	board[60] = Piece{Type: King, Color: Black} // e8
+	g.SetBoard(board)
 
 	g.Turn = White
 
@@ -191,11 +262,12 @@ func TestPawnPromotion(t *testing.T) {
 func TestPiecesCannotMoveThroughOthers(t *testing.T) {
 	g := NewGame()
 
-	g.Board = Board{}
-	g.Board[0] = Piece{Type: Rook, Color: White}  // a1
-	g.Board[1] = Piece{Type: Pawn, Color: White}  // b1
-	g.Board[60] = Piece{Type: King, Color: White} // e1
-	g.Board[4] = Piece{Type: King, Color: Black}  // e8
+	var board Board
+	board[0] = Piece{Type: Rook, Color: White}  // a1
+	board[1] = Piece{Type: Pawn, Color: White}  // b1
+	board[4] = Piece{Type: King, Color: White}  // e1
+	board[60] = Piece{Type: King, Color: Black} // e8
+	g.SetBoard(board)
 
 	g.Turn = White
 
@@ -214,13 +286,13 @@ func TestCheckmateDetection(t *testing.T) {
 	g := NewGame()
 
 	// Back rank mate
-	g.Board = Board{}
-	g.Board[7] = Piece{Type: King, Color: Black}  // h8
-	g.Board[6] = Piece{Type: Pawn, Color: Black}  // g8
-	g.Board[15] = Piece{Type: Pawn, Color: Black} // h7
-	g.Board[14] = Piece{Type: Pawn, Color: Black} // g7
-	g.Board[23] = Piece{Type: Rook, Color: White} // h6 - delivers checkmate
-	g.Board[60] = Piece{Type: King, Color: White} // e1
+	var board Board
+	board[63] = Piece{Type: King, Color: Black} // h8
+	board[55] = Piece{Type: Pawn, Color: Black} // h7
+	board[54] = Piece{Type: Pawn, Color: Black} // g7
+	board[56] = Piece{Type: Rook, Color: White} // a8 - delivers checkmate
+	board[4] = Piece{Type: King, Color: White}  // e1
+	g.SetBoard(board)
 
 	g.Turn = Black
 
@@ -239,11 +311,12 @@ func TestCheckmateDetection(t *testing.T) {
 func TestStalemateDetection(t *testing.T) {
 	g := NewGame()
 
-	// King + pawn vs king stalemate
-	g.Board = Board{}
-	g.Board[0] = Piece{Type: King, Color: Black}   // a1
-	g.Board[9] = Piece{Type: King, Color: White}   // b2
-	g.Board[16] = Piece{Type: Queen, Color: White} // a3
+	// King + queen vs king stalemate
+	var board Board
+	board[56] = Piece{Type: King, Color: Black}  // a8
+	board[4] = Piece{Type: King, Color: White}   // e1
+	board[41] = Piece{Type: Queen, Color: White} // b6
+	g.SetBoard(board)
 
 	g.Turn = Black
 
@@ -262,16 +335,17 @@ func TestStalemateDetection(t *testing.T) {
 func TestCastlingRightsLostAfterKingMove(t *testing.T) {
 	g := NewGame()
 
-	g.Board = Board{}
-	g.Board[60] = Piece{Type: King, Color: White} // e1
-	g.Board[63] = Piece{Type: Rook, Color: White} // h1
-	g.Board[4] = Piece{Type: King, Color: Black}  // e8
+	var board Board
+	board[4] = Piece{Type: King, Color: White}  // e1
+	board[7] = Piece{Type: Rook, Color: White}  // h1
+	board[60] = Piece{Type: King, Color: Black} // e8
+	g.SetBoard(board)
 
 	g.Turn = White
 	g.Castling.WhiteKingSide = true
 
 	// Move king
-	move := Move{From: 60, To: 61, Piece: King}
+	move := Move{From: 4, To: 5, Piece: King}
 	g.MakeMove(move)
 
 	if g.Castling.WhiteKingSide {
@@ -283,16 +357,17 @@ func TestCastlingRightsLostAfterKingMove(t *testing.T) {
 func TestCastlingRightsLostAfterRookMove(t *testing.T) {
 	g := NewGame()
 
-	g.Board = Board{}
-	g.Board[60] = Piece{Type: King, Color: White} // e1
-	g.Board[63] = Piece{Type: Rook, Color: White} // h1
-	g.Board[4] = Piece{Type: King, Color: Black}  // e8
+	var board Board
+	board[4] = Piece{Type: King, Color: White}  // e1
+	board[7] = Piece{Type: Rook, Color: White}  // h1
+	board[60] = Piece{Type: King, Color: Black} // e8
+	g.SetBoard(board)
 
 	g.Turn = White
 	g.Castling.WhiteKingSide = true
 
 	// Move rook
-	move := Move{From: 63, To: 62, Piece: Rook}
+	move := Move{From: 7, To: 6, Piece: Rook}
 	g.MakeMove(move)
 
 	if g.Castling.WhiteKingSide {
@@ -304,10 +379,11 @@ func TestCastlingRightsLostAfterRookMove(t *testing.T) {
 func TestKingCannotMoveIntoCheck(t *testing.T) {
 	g := NewGame()
 
-	g.Board = Board{}
-	g.Board[60] = Piece{Type: King, Color: White} // e1
-	g.Board[13] = Piece{Type: Rook, Color: Black} // f2 - controls f1
-	g.Board[4] = Piece{Type: King, Color: Black}  // e8
+	var board Board
+	board[4] = Piece{Type: King, Color: White}  // e1
+	board[13] = Piece{Type: Rook, Color: Black} // f2 - controls f1
+	board[60] = Piece{Type: King, Color: Black} // e8
+	g.SetBoard(board)
 
 	g.Turn = White
 
@@ -315,8 +391,44 @@ func TestKingCannotMoveIntoCheck(t *testing.T) {
 
 	// King should not be able to move to f1
 	for _, m := range legalMoves {
-		if m.From == 60 && m.To == 61 {
+		if m.From == 4 && m.To == 5 {
 			t.Error("King should not be able to move into check (f1)")
 		}
 	}
 }
+
+func TestRetractMoveRestoresPosition(t *testing.T) {
+	for _, pos := range perftPositions {
+		g := NewGame()
+		if err := g.LoadFEN(pos.fen); err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range g.GenerateLegalMoves() {
+			before, fen := g.Board, g.FEN()
+			snapshot := g.playMove(m)
+			for _, reply := range g.GenerateLegalMoves() {
+				g.retractMove(g.playMove(reply))
+			}
+			g.retractMove(snapshot)
+			if g.Board != before || g.FEN() != fen {
+				t.Fatalf("%s: %s not taken back: got %s, want %s", pos.name, g.UCI(m), g.FEN(), fen)
+			}
+		}
+	}
+}
+
+// Test that captured pieces are read off the moves of the current line
+func TestCapturedPieces(t *testing.T) {
+	g := NewGame()
+	playSAN(t, g, "e4", "a6", "e5", "d5", "exd6", "cxd6")
+
+	want := []Piece{{Type: Pawn, Color: Black}, {Type: Pawn, Color: White}}
+	if got := g.CapturedPieces(); len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	g.Back()
+	g.Back()
+	if got := g.CapturedPieces(); len(got) != 0 {
+		t.Errorf("Expected no captures before 3. exd6, got %v", got)
+	}
+}
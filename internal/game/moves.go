@@ -1,314 +1,260 @@
 package game
 
-func (g *Game) GenerateLegalMoves() []Move {
-	pseudoMoves := g.GeneratePseudoLegalMoves()
-	legalMoves := []Move{}
+// promotionPieces lists the promotion choices in the order they are generated
+var promotionPieces = []PieceType{Queen, Rook, Bishop, Knight}
+
+// commonerPromotionPieces adds the king, which pawns may promote to where it
+// is an ordinary piece
+var commonerPromotionPieces = []PieceType{Queen, Rook, Bishop, Knight, King}
 
-	for _, m := range pseudoMoves {
-		if g.isMoveLegal(m) {
-			legalMoves = append(legalMoves, m)
+func (g *Game) GenerateLegalMoves() []Move {
+	bb := &g.bb
+	pseudoMoves := g.pseudoLegalMoves(bb, make([]Move, 0, 64))
+
+	// Filter in place; legality never depends on the moves already dropped.
+	// Atomic plays every move out to see what explodes. Otherwise, without a
+	// royal king every move is legal, as is any move of a side without a
+	// king, like the horde in Horde.
+	legalMoves := pseudoMoves
+	switch rules := g.rules(); {
+	case rules.Explodes():
+		legalMoves = pseudoMoves[:0]
+		for _, m := range pseudoMoves {
+			if g.leavesKingSafe(bb, m) {
+				legalMoves = append(legalMoves, m)
+			}
+		}
+	case !rules.RoyalKing() || bb.pieces[g.Turn][King] == 0:
+	default:
+		pins := bb.pins(g.Turn)
+		legalMoves = pseudoMoves[:0]
+		for _, m := range pseudoMoves {
+			if g.isLegal(bb, &pins, m) {
+				legalMoves = append(legalMoves, m)
+			}
 		}
 	}
 
+	if g.Variant != nil {
+		return g.Variant.FilterMoves(g, legalMoves)
+	}
 	return legalMoves
 }
 
 // isMoveLegal checks if a move is legal by simulating it and checking if king is safe
 func (g *Game) isMoveLegal(m Move) bool {
-	// Create a temporary board copy
-	tempBoard := g.Board
+	return g.leavesKingSafe(&g.bb, m)
+}
 
-	// Store original en passant target for restoration
-	originalEP := g.EnPassantTarget
+// leavesKingSafe plays m on a copy of the piece sets and checks that the
+// mover's king is not attacked afterwards
+func (g *Game) leavesKingSafe(bb *bitboards, m Move) bool {
+	after := *bb
+	after.play(g, m)
+	if g.rules().Explodes() {
+		return after.survivesExplosion(g.Turn)
+	}
+	return !after.inCheck(g.Turn)
+}
 
-	// Execute move on temp board
-	tempBoard[m.To] = tempBoard[m.From]
-	tempBoard[m.From] = Piece{Type: Empty}
+// pinState holds what the legality of a move depends on, worked out once per
+// position for the side to move
+type pinState struct {
+	king      int
+	checkers  Bitboard // Enemy pieces giving check
+	pinned    Bitboard // Our pieces that cannot leave the line to the king
+	checkMask Bitboard // Where a move other than the king's must land: on the checker or between it and the king, or anywhere when not in check
+}
 
-	if m.Promotion != Empty {
-		tempBoard[m.To] = Piece{Type: m.Promotion, Color: g.Turn}
+// pins finds the checkers of c's king and the pieces of c pinned to it. c
+// must have a king.
+func (bb *bitboards) pins(c Color) pinState {
+	them := &bb.pieces[c.Opponent()]
+	ps := pinState{king: bb.pieces[c][King].LSB(), checkMask: ^Bitboard(0)}
+	ps.checkers = bb.attackersTo(ps.king, bb.all) & bb.colors[c.Opponent()]
+	if ps.checkers.Count() == 1 {
+		checker := ps.checkers.LSB()
+		ps.checkMask = between[ps.king][checker] | ps.checkers
 	}
 
-	// Handle En Passant Capture
-	if m.MoveType == MoveEnPassant {
-		var captureSq int
-		if g.Turn == White {
-			captureSq = m.To - 8
-		} else {
-			captureSq = m.To + 8
+	snipers := rookAttacks(ps.king, 0)&(them[Rook]|them[Queen]) |
+		bishopAttacks(ps.king, 0)&(them[Bishop]|them[Queen])
+	for snipers != 0 {
+		blockers := between[ps.king][snipers.PopLSB()] & bb.all
+		if blockers.Count() == 1 && blockers&bb.colors[c] != 0 {
+			ps.pinned |= blockers
 		}
-		tempBoard[captureSq] = Piece{Type: Empty}
 	}
+	return ps
+}
 
-	// Handle Castling Rook Move
-	if m.MoveType == MoveCastling {
-		switch m.To {
-		case 6: // White Short (e1 -> g1)
-			tempBoard[5] = tempBoard[7] // Move Rook h1 -> f1
-			tempBoard[7] = Piece{Type: Empty}
-		case 2: // White Long (e1 -> c1)
-			tempBoard[3] = tempBoard[0] // Move Rook a1 -> d1
-			tempBoard[0] = Piece{Type: Empty}
-		case 62: // Black Short (e8 -> g8)
-			tempBoard[61] = tempBoard[63] // Move Rook h8 -> f8
-			tempBoard[63] = Piece{Type: Empty}
-		case 58: // Black Long (e8 -> c8)
-			tempBoard[59] = tempBoard[56] // Move Rook a8 -> d8
-			tempBoard[56] = Piece{Type: Empty}
-		}
+// isLegal reports whether the pseudo-legal move m keeps the king of the side
+// to move safe, without playing it. Only castling, where the rook may uncover
+// an attack in Chess960, is played out on a copy of the piece sets.
+func (g *Game) isLegal(bb *bitboards, ps *pinState, m Move) bool {
+	them := g.Turn.Opponent()
+	switch {
+	case m.MoveType == MoveCastling:
+		return g.leavesKingSafe(bb, m)
+	case m.MoveType != MoveDrop && m.From == ps.king:
+		// The king must not shield the squares behind it from a slider
+		occupied := bb.all &^ squareBB(ps.king)
+		return bb.attackersTo(m.To, occupied)&bb.colors[them] == 0
+	case ps.checkers.Count() > 1:
+		return false
+	case m.MoveType == MoveEnPassant:
+		// Both pawns leave the rank, which may expose the king along it, so
+		// look at the occupancy after the capture
+		captured := squareBB(enPassantCaptureSquare(m.To, g.Turn))
+		occupied := bb.all&^squareBB(m.From)&^captured | squareBB(m.To)
+		return bb.attackersTo(ps.king, occupied)&bb.colors[them]&^captured == 0
+	case !ps.checkMask.Has(m.To):
+		return false
+	case m.MoveType != MoveDrop && ps.pinned.Has(m.From):
+		return line[ps.king][m.From].Has(m.To)
 	}
-
-	// Verify King Safety
-	isLegal := !tempBoard.InCheck(g.Turn)
-
-	// Restore en passant target
-	g.EnPassantTarget = originalEP
-
-	return isLegal
+	return true
 }
 
 func (g *Game) GeneratePseudoLegalMoves() []Move {
-	moves := []Move{}
-	turn := g.Turn
-	b := g.Board
+	return g.pseudoLegalMoves(&g.bb, []Move{})
+}
 
-	for sq := 0; sq < 64; sq++ {
-		piece := b[sq]
+// pseudoLegalMoves appends every move of the side to move that obeys the
+// piece movement rules, without checking king safety. Royal kings are never
+// captured, and in Atomic kings capture nothing.
+func (g *Game) pseudoLegalMoves(bb *bitboards, moves []Move) []Move {
+	us, them := g.Turn, g.Turn.Opponent()
+	rules := g.rules()
+	targets := ^bb.colors[us]
+	if rules.RoyalKing() {
+		targets &^= bb.pieces[them][King]
+	}
 
-		if piece.Type == Empty || piece.Color != turn {
-			continue
-		}
+	moves = g.pawnMoves(bb, moves)
 
-		switch piece.Type {
-		case Pawn:
-			moves = append(moves, g.getPawnMoves(sq)...)
-		case Knight:
-			moves = append(moves, b.getKnightMoves(sq)...)
-		case Bishop, Rook, Queen:
-			moves = append(moves, b.getSlidingMoves(sq)...)
-		case King:
-			moves = append(moves, g.getKingMoves(sq)...)
+	for pt := Knight; pt <= King; pt++ {
+		pieceTargets := targets
+		if pt == King && rules.Explodes() {
+			pieceTargets &^= bb.colors[them]
+		}
+		pieces := bb.pieces[us][pt]
+		for pieces != 0 {
+			from := pieces.PopLSB()
+			attacks := pieceAttacks(pt, from, bb.all) & pieceTargets
+			for attacks != 0 {
+				moves = append(moves, Move{From: from, To: attacks.PopLSB(), Piece: pt})
+			}
 		}
 	}
-	return moves
-}
 
-// --- Stepping Pieces (Knight, King) ---
-
-func (b *Board) getKnightMoves(sq int) []Move {
-	moves := []Move{}
-	offsets := [][2]int{
-		{1, 2}, {1, -2}, {-1, 2}, {-1, -2},
-		{2, 1}, {2, -1}, {-2, 1}, {-2, -1},
+	if rules.RoyalKing() {
+		moves = g.castlingMoves(bb, moves)
+	}
+	if g.Variant != nil && g.Variant.HasPockets() {
+		moves = g.dropMoves(bb, moves)
 	}
-	b.addSteppingMoves(sq, offsets, &moves)
 	return moves
 }
 
-func (g *Game) getKingMoves(sq int) []Move {
-	moves := []Move{}
-	b := g.Board
+// --- Pawns ---
 
-	// 1. Normal King Moves
-	offsets := [][2]int{
-		{0, 1}, {0, -1}, {1, 0}, {-1, 0},
-		{1, 1}, {1, -1}, {-1, 1}, {-1, -1},
+func (g *Game) pawnMoves(bb *bitboards, moves []Move) []Move {
+	us, them := g.Turn, g.Turn.Opponent()
+	enemies := bb.colors[them] &^ bb.pieces[them][King]
+	promotions := promotionPieces
+	if !g.rules().RoyalKing() {
+		enemies = bb.colors[them]
+		promotions = commonerPromotionPieces
 	}
-	b.addSteppingMoves(sq, offsets, &moves)
 
-	// 2. Castling - only add if not in check
-	if b.InCheck(g.Turn) {
-		return moves
+	direction, backRank, startRank, promotionRank := 8, 0, 1, 7
+	if us == Black {
+		direction, backRank, startRank, promotionRank = -8, 7, 6, 0
 	}
 
-	if g.Turn == White {
-		// White Short Castling (e1 -> g1)
-		if g.Castling.WhiteKingSide && sq == 4 { // King must be on e1 (index 4)
-			if b[5].Type == Empty && b[6].Type == Empty && // f1 and g1 empty
-				b[7].Type == Rook && b[7].Color == White { // Rook on h1 (index 7)
-				// Check that f1 and g1 are not attacked
-				if !b.IsSquareAttacked(5, Black) && !b.IsSquareAttacked(6, Black) {
-					moves = append(moves, Move{From: sq, To: 6, Piece: King, MoveType: MoveCastling})
-				}
-			}
-		}
+	pawns := bb.pieces[us][Pawn]
+	for pawns != 0 {
+		from := pawns.PopLSB()
 
-		// White Long Castling (e1 -> c1)
-		if g.Castling.WhiteQueenSide && sq == 4 { // King must be on e1
-			if b[3].Type == Empty && b[2].Type == Empty && b[1].Type == Empty && // d1, c1, b1 empty
-				b[0].Type == Rook && b[0].Color == White { // Rook on a1 (index 0)
-				// Check that d1 and c1 are not attacked
-				if !b.IsSquareAttacked(3, Black) && !b.IsSquareAttacked(2, Black) {
-					moves = append(moves, Move{From: sq, To: 2, Piece: King, MoveType: MoveCastling})
-				}
+		// 1. Forward moves, including the double step from the start rank,
+		// or from the first rank where Horde puts pawns
+		if to := from + direction; !bb.all.Has(to) {
+			moves = appendPawnMove(moves, from, to, to/8 == promotionRank, promotions)
+			if double := to + direction; (from/8 == startRank || from/8 == backRank) && !bb.all.Has(double) {
+				moves = append(moves, Move{From: from, To: double, Piece: Pawn})
 			}
 		}
-	} else {
-		// Black Short Castling (e8 -> g8)
-		if g.Castling.BlackKingSide && sq == 60 { // King must be on e8 (index 60)
-			if b[61].Type == Empty && b[62].Type == Empty && // f8 and g8 empty
-				b[63].Type == Rook && b[63].Color == Black { // Rook on h8 (index 63)
-				// Check that f8 and g8 are not attacked
-				if !b.IsSquareAttacked(61, White) && !b.IsSquareAttacked(62, White) {
-					moves = append(moves, Move{From: sq, To: 62, Piece: King, MoveType: MoveCastling})
-				}
-			}
+
+		// 2. Captures
+		attacks := pawnAttacks[us][from]
+		captures := attacks & enemies
+		for captures != 0 {
+			to := captures.PopLSB()
+			moves = appendPawnMove(moves, from, to, to/8 == promotionRank, promotions)
 		}
 
-		// Black Long Castling (e8 -> c8)
-		if g.Castling.BlackQueenSide && sq == 60 { // King must be on e8
-			if b[59].Type == Empty && b[58].Type == Empty && b[57].Type == Empty && // d8, c8, b8 empty
-				b[56].Type == Rook && b[56].Color == Black { // Rook on a8 (index 56)
-				// Check that d8 and c8 are not attacked
-				if !b.IsSquareAttacked(59, White) && !b.IsSquareAttacked(58, White) {
-					moves = append(moves, Move{From: sq, To: 58, Piece: King, MoveType: MoveCastling})
-				}
-			}
+		// 3. En passant onto the empty square behind a double-stepped pawn
+		if ep := g.EnPassantTarget; ep >= 0 && attacks.Has(ep) && !bb.all.Has(ep) {
+			moves = append(moves, Move{From: from, To: ep, Piece: Pawn, MoveType: MoveEnPassant})
 		}
 	}
 
 	return moves
 }
 
-func (b *Board) addSteppingMoves(sq int, offsets [][2]int, moves *[]Move) {
-	startRank := sq / 8
-	startFile := sq % 8
-	movingPiece := b[sq]
-
-	for _, off := range offsets {
-		dFile, dRank := off[0], off[1]
-		targetRank := startRank + dRank
-		targetFile := startFile + dFile
-
-		if targetRank >= 0 && targetRank < 8 && targetFile >= 0 && targetFile < 8 {
-			targetSq := targetRank*8 + targetFile
-			targetPiece := b[targetSq]
-
-			// Can only move to empty square or capture opponent's piece (NOT own piece)
-			if targetPiece.Type == Empty || targetPiece.Color != movingPiece.Color {
-				*moves = append(*moves, Move{From: sq, To: targetSq, Piece: movingPiece.Type})
-			}
-		}
-	}
-}
-
-// --- Sliding Pieces ---
-
-func (b *Board) getSlidingMoves(sq int) []Move {
-	moves := []Move{}
-	piece := b[sq]
-	var directions [][2]int
-
-	if piece.Type == Bishop || piece.Type == Queen {
-		directions = append(directions, [][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}...)
+// appendPawnMove adds a pawn move, or one move per piece when it promotes
+func appendPawnMove(moves []Move, from, to int, promotes bool, promotions []PieceType) []Move {
+	if !promotes {
+		return append(moves, Move{From: from, To: to, Piece: Pawn})
 	}
-	if piece.Type == Rook || piece.Type == Queen {
-		directions = append(directions, [][2]int{{0, 1}, {0, -1}, {1, 0}, {-1, 0}}...)
-	}
-
-	startRank := sq / 8
-	startFile := sq % 8
-
-	for _, dir := range directions {
-		dFile, dRank := dir[0], dir[1]
-		for i := 1; i < 8; i++ {
-			targetRank := startRank + (dRank * i)
-			targetFile := startFile + (dFile * i)
-
-			if targetRank < 0 || targetRank > 7 || targetFile < 0 || targetFile > 7 {
-				break
-			}
-
-			targetSq := targetRank*8 + targetFile
-			targetPiece := b[targetSq]
-
-			if targetPiece.Type == Empty {
-				moves = append(moves, Move{From: sq, To: targetSq, Piece: piece.Type})
-			} else {
-				// Can capture opponent's piece, but not own piece
-				if targetPiece.Color != piece.Color {
-					moves = append(moves, Move{From: sq, To: targetSq, Piece: piece.Type})
-				}
-				break // Blocked by any piece
-			}
-		}
+	for _, p := range promotions {
+		moves = append(moves, Move{From: from, To: to, Piece: Pawn, Promotion: p})
 	}
 	return moves
 }
 
-// --- Pawns ---
-
-func (g *Game) getPawnMoves(sq int) []Move {
-	moves := []Move{}
-	b := g.Board
-	piece := b[sq]
-
-	rank := sq / 8
-	file := sq % 8
-
-	direction := 1
-	startRank := 1
-	promotionRank := 7
-
-	if piece.Color == Black {
-		direction = -1
-		startRank = 6
-		promotionRank = 0
+// --- Castling ---
+
+// castlingMoves adds castling when the right is kept, the king and its rook
+// are on the back rank, every square either of them crosses or lands on is
+// empty apart from the two of them, and the king is not in check and does
+// not pass through an attacked square. This covers Chess960, where the king
+// and rook may start anywhere on the back rank. As for every move, the king
+// must also be safe once the rook has moved, which leavesKingSafe checks.
+// In Atomic a square next to the enemy king is never attacked, since
+// capturing there would blow up both kings.
+func (g *Game) castlingMoves(bb *bitboards, moves []Move) []Move {
+	us, them := g.Turn, g.Turn.Opponent()
+	explodes := g.rules().Explodes()
+	if bb.pieces[us][King] == 0 {
+		return moves
 	}
+	king := bb.pieces[us][King].LSB()
 
-	// 1. Forward Move
-	targetRank := rank + direction
-	if targetRank >= 0 && targetRank < 8 {
-		targetSq := targetRank*8 + file
-		if b[targetSq].Type == Empty {
-			// Check for promotion
-			if targetRank == promotionRank {
-				promotions := []PieceType{Queen, Rook, Bishop, Knight}
-				for _, p := range promotions {
-					moves = append(moves, Move{From: sq, To: targetSq, Piece: Pawn, Promotion: p})
-				}
-			} else {
-				moves = append(moves, Move{From: sq, To: targetSq, Piece: Pawn})
-			}
-
-			// 2. Double Move from starting position
-			if rank == startRank {
-				doubleRank := rank + (2 * direction)
-				doubleSq := doubleRank*8 + file
-				if b[doubleSq].Type == Empty {
-					moves = append(moves, Move{From: sq, To: doubleSq, Piece: Pawn})
-				}
-			}
+	for _, kingSide := range [2]bool{true, false} {
+		rook := g.castlingRook(us, kingSide)
+		if !*g.Castling.right(us, kingSide) || !bb.pieces[us][Rook].Has(rook) || rook/8 != king/8 {
+			continue
 		}
-	}
-
-	// 3. Captures (Normal + En Passant)
-	captureOffsets := []int{-1, 1}
-	for _, off := range captureOffsets {
-		captureFile := file + off
-		if captureFile >= 0 && captureFile < 8 {
-			targetRank := rank + direction
-			if targetRank >= 0 && targetRank < 8 {
-				targetSq := targetRank*8 + captureFile
-				targetPiece := b[targetSq]
 
-				// Normal Capture - must be opponent's piece
-				if targetPiece.Type != Empty && targetPiece.Color != piece.Color {
-					if targetRank == promotionRank {
-						promotions := []PieceType{Queen, Rook, Bishop, Knight}
-						for _, p := range promotions {
-							moves = append(moves, Move{From: sq, To: targetSq, Piece: Pawn, Promotion: p})
-						}
-					} else {
-						moves = append(moves, Move{From: sq, To: targetSq, Piece: Pawn})
-					}
-				}
+		kingTo, rookTo := castlingTargets(rook, kingSide)
+		kingPath := rankSpan(king, kingTo)
+		others := bb.all &^ squareBB(king) &^ squareBB(rook)
+		if (kingPath|rankSpan(rook, rookTo))&others != 0 {
+			continue
+		}
 
-				// En Passant Capture
-				if targetPiece.Type == Empty && targetSq == g.EnPassantTarget {
-					moves = append(moves, Move{From: sq, To: targetSq, Piece: Pawn, MoveType: MoveEnPassant})
-				}
-			}
+		// The king does not shield the squares behind it from its own path
+		withoutKing := *bb
+		withoutKing.all &^= squareBB(king)
+		safe := true
+		for path := kingPath; path != 0 && safe; {
+			sq := path.PopLSB()
+			safe = !withoutKing.isAttacked(sq, them) || (explodes && kingAttacks[sq]&bb.pieces[them][King] != 0)
+		}
+		if safe {
+			moves = append(moves, Move{From: king, To: kingTo, Piece: King, MoveType: MoveCastling})
 		}
 	}
 
@@ -0,0 +1,265 @@
+package game
+
+import "fmt"
+
+// Result is the final score of a game
+type Result int
+
+const (
+	NoResult Result = iota // Game still in progress
+	WhiteWins
+	BlackWins
+	Draw
+)
+
+// String returns the PGN result token ("1-0", "0-1", "1/2-1/2" or "*")
+func (r Result) String() string {
+	switch r {
+	case WhiteWins:
+		return "1-0"
+	case BlackWins:
+		return "0-1"
+	case Draw:
+		return "1/2-1/2"
+	default:
+		return "*"
+	}
+}
+
+// Termination describes why a game ended
+type Termination int
+
+const (
+	TerminationNone Termination = iota
+	TerminationCheckmate
+	TerminationStalemate
+	TerminationThreefoldRepetition
+	TerminationFivefoldRepetition
+	TerminationFiftyMoveRule
+	TerminationSeventyFiveMoveRule
+	TerminationInsufficientMaterial
+	TerminationResignation
+	TerminationTimeout
+	TerminationAgreement
+	TerminationKingOfTheHill
+	TerminationThreeCheck
+	TerminationExplosion     // A king was blown up in Atomic
+	TerminationAllPiecesLost // A player lost every piece in Antichess, which wins
+	TerminationHordeCaptured // Black captured every White piece in Horde
+	TerminationRacingKings   // A king reached the eighth rank in Racing Kings
+)
+
+func (t Termination) String() string {
+	switch t {
+	case TerminationCheckmate:
+		return "checkmate"
+	case TerminationStalemate:
+		return "stalemate"
+	case TerminationThreefoldRepetition:
+		return "threefold-repetition"
+	case TerminationFivefoldRepetition:
+		return "fivefold-repetition"
+	case TerminationFiftyMoveRule:
+		return "fifty-move"
+	case TerminationSeventyFiveMoveRule:
+		return "seventy-five-move"
+	case TerminationInsufficientMaterial:
+		return "insufficient-material"
+	case TerminationResignation:
+		return "resignation"
+	case TerminationTimeout:
+		return "timeout"
+	case TerminationAgreement:
+		return "agreement"
+	case TerminationKingOfTheHill:
+		return "king-of-the-hill"
+	case TerminationThreeCheck:
+		return "three-check"
+	case TerminationExplosion:
+		return "explosion"
+	case TerminationAllPiecesLost:
+		return "all-pieces-lost"
+	case TerminationHordeCaptured:
+		return "horde-captured"
+	case TerminationRacingKings:
+		return "racing-kings"
+	default:
+		return ""
+	}
+}
+
+// Outcome is the result of a game together with the reason it ended
+type Outcome struct {
+	Result      Result
+	Termination Termination
+}
+
+// IsOver reports whether the game has ended
+func (o Outcome) IsOver() bool {
+	return o.Result != NoResult
+}
+
+// Winner returns the winning color, or false if the game is drawn or still running
+func (o Outcome) Winner() (Color, bool) {
+	switch o.Result {
+	case WhiteWins:
+		return White, true
+	case BlackWins:
+		return Black, true
+	}
+	return White, false
+}
+
+// String describes the outcome for display, e.g. "White wins by checkmate"
+func (o Outcome) String() string {
+	switch o.Result {
+	case WhiteWins, BlackWins:
+		winner, _ := o.Winner()
+		return fmt.Sprintf("%s wins by %s", winner, o.Termination)
+	case Draw:
+		return fmt.Sprintf("Draw by %s", o.Termination)
+	default:
+		return "Game in progress"
+	}
+}
+
+// winFor returns the result in which the given color wins
+func winFor(c Color) Result {
+	if c == White {
+		return WhiteWins
+	}
+	return BlackWins
+}
+
+// Outcome reports whether the game has ended and why. Draws that must be
+// claimed (threefold repetition, fifty-move rule) only count once ClaimDraw
+// has been called; automatic draws are reported as soon as they occur.
+func (g *Game) Outcome() Outcome {
+	if g.declared.IsOver() {
+		return g.declared
+	}
+	if o := g.rules().Outcome(g); o.IsOver() {
+		return o
+	}
+
+	if len(g.GenerateLegalMoves()) == 0 {
+		if g.InCheck() {
+			return Outcome{Result: winFor(g.Turn.Opponent()), Termination: TerminationCheckmate}
+		}
+		return Outcome{Result: Draw, Termination: TerminationStalemate}
+	}
+
+	if g.rules().InsufficientMaterial(g) {
+		return Outcome{Result: Draw, Termination: TerminationInsufficientMaterial}
+	}
+	if g.IsFivefoldRepetition() {
+		return Outcome{Result: Draw, Termination: TerminationFivefoldRepetition}
+	}
+	if g.IsSeventyFiveMoveDraw() {
+		return Outcome{Result: Draw, Termination: TerminationSeventyFiveMoveRule}
+	}
+
+	return Outcome{}
+}
+
+// ClaimableDraw returns the rule under which the side to move may claim a
+// draw, or TerminationNone if no claim is available
+func (g *Game) ClaimableDraw() Termination {
+	if g.CanClaimThreefoldRepetition() {
+		return TerminationThreefoldRepetition
+	}
+	if g.CanClaimFiftyMoveDraw() {
+		return TerminationFiftyMoveRule
+	}
+	return TerminationNone
+}
+
+// ClaimDraw ends the game as a draw by threefold repetition or the fifty-move rule
+func (g *Game) ClaimDraw() error {
+	if g.Outcome().IsOver() {
+		return fmt.Errorf("game is already over")
+	}
+
+	reason := g.ClaimableDraw()
+	if reason == TerminationNone {
+		return fmt.Errorf("no draw can be claimed in this position")
+	}
+
+	g.declare(Outcome{Result: Draw, Termination: reason})
+	return nil
+}
+
+// Resign ends the game with a win for the opponent of the given color
+func (g *Game) Resign(c Color) error {
+	if g.Outcome().IsOver() {
+		return fmt.Errorf("game is already over")
+	}
+	g.declare(Outcome{Result: winFor(c.Opponent()), Termination: TerminationResignation})
+	return nil
+}
+
+// AgreeDraw ends the game as a draw by mutual agreement
+func (g *Game) AgreeDraw() error {
+	if g.Outcome().IsOver() {
+		return fmt.Errorf("game is already over")
+	}
+	g.declare(Outcome{Result: Draw, Termination: TerminationAgreement})
+	return nil
+}
+
+// FlagTimeout ends the game because the given color ran out of time. The
+// opponent wins unless no series of legal moves could ever let it win, in
+// which case the game is drawn.
+func (g *Game) FlagTimeout(c Color) error {
+	if g.Outcome().IsOver() {
+		return fmt.Errorf("game is already over")
+	}
+
+	result := winFor(c.Opponent())
+	if !g.rules().CanWin(g, c.Opponent()) {
+		result = Draw
+	}
+	g.declare(Outcome{Result: result, Termination: TerminationTimeout})
+	return nil
+}
+
+// declare ends the game with o and records it on the current node, so the
+// result comes back when the game returns to this position in the move tree
+func (g *Game) declare(o Outcome) {
+	g.declared = o
+	if g.current != nil {
+		g.current.declared = o
+	}
+}
+
+// hasMatingMaterial reports whether color c could checkmate with help from
+// the opponent. A lone king never can. A lone knight needs an enemy piece
+// other than the queen to block its victim's king in, and bishops all on one
+// square colour need an enemy knight, pawn or bishop on the other colour.
+// Anything more is always enough.
+func (b *Board) hasMatingMaterial(c Color) bool {
+	var own, theirs [7]int
+	bishopSquareColors := [2]bool{}
+	for sq, p := range b {
+		switch {
+		case p.Type == Empty:
+		case p.Color == c:
+			own[p.Type]++
+		default:
+			theirs[p.Type]++
+		}
+		if p.Type == Bishop {
+			bishopSquareColors[(sq/8+sq%8)%2] = true
+		}
+	}
+
+	switch {
+	case own[Pawn] > 0 || own[Rook] > 0 || own[Queen] > 0:
+		return true
+	case own[Knight] == 1 && own[Bishop] == 0:
+		return theirs[Pawn]+theirs[Knight]+theirs[Bishop]+theirs[Rook] > 0
+	case own[Knight] == 0 && own[Bishop] > 0:
+		return theirs[Pawn]+theirs[Knight] > 0 || (bishopSquareColors[0] && bishopSquareColors[1])
+	}
+	return own[Knight] > 0
+}
@@ -0,0 +1,66 @@
+package game
+
+import "strings"
+
+// Perft counts the leaf nodes of the legal move tree to the given depth.
+// Comparing the counts with published values is the standard check of a
+// move generator.
+func (g *Game) Perft(depth int) uint64 {
+	return g.position().perft(depth)
+}
+
+func (g *Game) perft(depth int) uint64 {
+	if depth == 0 {
+		return 1
+	}
+
+	moves := g.GenerateLegalMoves()
+	if depth == 1 {
+		return uint64(len(moves))
+	}
+
+	var nodes uint64
+	for _, m := range moves {
+		snapshot := g.playMove(m)
+		nodes += g.perft(depth - 1)
+		g.retractMove(snapshot)
+	}
+	return nodes
+}
+
+// Divide returns the perft count below each legal move, keyed by the move in
+// UCI notation, to narrow a wrong total down to the moves that differ from a
+// reference engine
+func (g *Game) Divide(depth int) map[string]uint64 {
+	pos := g.position()
+	counts := make(map[string]uint64)
+	if depth < 1 {
+		return counts
+	}
+
+	for _, m := range pos.GenerateLegalMoves() {
+		uci := pos.UCI(m)
+		snapshot := pos.playMove(m)
+		counts[uci] = pos.perft(depth - 1)
+		pos.retractMove(snapshot)
+	}
+	return counts
+}
+
+// UCI returns the move in coordinate notation, e.g. "e2e4" or "e7e8q". In
+// Chess960 castling is written as the king taking its own rook, e.g. "e1h1",
+// and drops as the piece and its square, e.g. "N@f3".
+func (g *Game) UCI(m Move) string {
+	if m.MoveType == MoveDrop {
+		return dropSAN(m)
+	}
+	to := m.To
+	if m.MoveType == MoveCastling && g.Chess960 {
+		to, _ = g.castlingRookSquares(m)
+	}
+	uci := IndexToCoord(m.From) + IndexToCoord(to)
+	if m.Promotion != Empty {
+		uci += strings.ToLower(pieceLetter(m.Promotion))
+	}
+	return uci
+}
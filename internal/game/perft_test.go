@@ -0,0 +1,131 @@
+package game
+
+import "testing"
+
+// perftPositions are the standard move generator test positions with their
+// published node counts, indexed by depth-1
+var perftPositions = []struct {
+	name   string
+	fen    string
+	counts []uint64
+}{
+	{"start", StartFEN, []uint64{20, 400, 8902, 197281}},
+	{"kiwipete", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", []uint64{48, 2039, 97862}},
+	{"position 3", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", []uint64{14, 191, 2812, 43238}},
+	{"position 4", "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", []uint64{6, 264, 9467}},
+	{"position 5", "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", []uint64{44, 1486, 62379}},
+	{"position 6", "r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10", []uint64{46, 2079, 89890}},
+}
+
+// Test the move generator against the published perft counts
+func TestPerft(t *testing.T) {
+	for _, pos := range perftPositions {
+		g := NewGame()
+		if err := g.LoadFEN(pos.fen); err != nil {
+			t.Fatalf("%s: %v", pos.name, err)
+		}
+		for i, want := range pos.counts {
+			depth := i + 1
+			if testing.Short() && want > 10000 {
+				break
+			}
+			if got := g.Perft(depth); got != want {
+				t.Errorf("%s: perft(%d) = %d, want %d", pos.name, depth, got, want)
+			}
+		}
+		if g.FEN() != pos.fen {
+			t.Errorf("%s: perft changed the position to %s", pos.name, g.FEN())
+		}
+	}
+}
+
+// Test that divide splits the perft total by root move
+func TestDivide(t *testing.T) {
+	g := NewGame()
+	counts := g.Divide(2)
+	if len(counts) != 20 || counts["e2e4"] != 20 || counts["g1f3"] != 20 {
+		t.Errorf("Unexpected divide counts: %v", counts)
+	}
+
+	var total uint64
+	for _, n := range counts {
+		total += n
+	}
+	if total != 400 {
+		t.Errorf("Expected divide to sum to 400, got %d", total)
+	}
+
+	g.LoadFEN("4k3/1P6/8/8/8/8/8/4K3 w - - 0 1")
+	if counts := g.Divide(1); counts["b7b8q"] != 1 || counts["b7b8n"] != 1 {
+		t.Errorf("Expected promotion moves in UCI notation, got %v", counts)
+	}
+}
+
+func BenchmarkPerftStart(b *testing.B) {
+	g := NewGame()
+	for i := 0; i < b.N; i++ {
+		g.Perft(3)
+	}
+}
+
+func BenchmarkPerftKiwipete(b *testing.B) {
+	g := NewGame()
+	g.LoadFEN(perftPositions[1].fen)
+	for i := 0; i < b.N; i++ {
+		g.Perft(2)
+	}
+}
+
+func BenchmarkGenerateLegalMoves(b *testing.B) {
+	g := NewGame()
+	g.LoadFEN(perftPositions[1].fen)
+	for i := 0; i < b.N; i++ {
+		g.GenerateLegalMoves()
+	}
+}
+
+func BenchmarkInCheck(b *testing.B) {
+	g := NewGame()
+	g.LoadFEN(perftPositions[1].fen)
+	for i := 0; i < b.N; i++ {
+		g.Board.InCheck(White)
+	}
+}
+
+// BenchmarkMakeUndoMove plays and takes back every legal move through the
+// public API, which also records history, SAN and move results
+func BenchmarkMakeUndoMove(b *testing.B) {
+	g := NewGame()
+	g.LoadFEN(perftPositions[1].fen)
+	moves := g.GenerateLegalMoves()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, m := range moves {
+			g.MakeMove(m)
+			g.UndoMove()
+		}
+	}
+}
+
+// BenchmarkPlayRetractMove is the same loop on the allocation-free path
+// used by search
+func BenchmarkPlayRetractMove(b *testing.B) {
+	g := NewGame()
+	g.LoadFEN(perftPositions[1].fen)
+	moves := g.GenerateLegalMoves()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, m := range moves {
+			g.retractMove(g.playMove(m))
+		}
+	}
+}
+
+func BenchmarkGetBestMove(b *testing.B) {
+	g := NewGame()
+	g.LoadFEN(perftPositions[1].fen)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		g.GetBestMove(3)
+	}
+}
@@ -2,103 +2,220 @@ package game
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 )
 
-// GeneratePGN creates a PGN string from the game history
+// pgnLineWidth is the longest movetext line written, so exported games fit in 80 columns
+const pgnLineWidth = 79
+
+// sevenTagRoster lists the mandatory PGN tags in their required order, with
+// the value written when a tag is unknown
+var sevenTagRoster = []struct {
+	Name    string
+	Default string
+}{
+	{"Event", "?"},
+	{"Site", "?"},
+	{"Date", "????.??.??"},
+	{"Round", "?"},
+	{"White", "?"},
+	{"Black", "?"},
+	{"Result", "*"},
+}
+
+// GeneratePGN creates a PGN string in export format: the Seven Tag Roster
+// (from Game.Tags, with "?" for unknown values), SetUp/FEN tags when the game
+// did not start from the variant's start position, a Variant tag for
+// variant and Chess960 games that lack one, any other tags in alphabetical
+// order, and movetext wrapped to 80 columns ending in the result. The whole
+// move tree is written, with variations in parentheses, and the result is the
+// one reached at the end of the main line wherever the game currently is.
 func (g *Game) GeneratePGN() string {
 	var sb strings.Builder
 
-	// Temporarily replay game to calculate moves accurately if needed,
-	// but we have MoveResults, so we can format directly.
-
-	for i, result := range g.MoveResults {
-		moveNum := (i / 2) + 1
+	// Replay the game from its starting position so every move is written
+	// in SAN relative to the position it was played in
+	replay := g.rootPosition()
+	result := g.mainLineOutcome().Result.String()
+
+	// 1. Tag pairs
+	written := map[string]bool{"SetUp": true, "FEN": true}
+	for _, tag := range sevenTagRoster {
+		value, ok := g.Tags[tag.Name]
+		if !ok || value == "" {
+			value = tag.Default
+		}
+		if tag.Name == "Result" {
+			value = result
+		}
+		writeTag(&sb, tag.Name, value)
+		written[tag.Name] = true
+	}
 
-		// Add "1. " for white's moves
-		if i%2 == 0 {
-			sb.WriteString(fmt.Sprintf("%d. ", moveNum))
+	if fen := replay.FEN(); fen != g.rules().StartFEN() {
+		writeTag(&sb, "SetUp", "1")
+		writeTag(&sb, "FEN", fen)
+	}
+	if _, ok := g.Tags["Variant"]; !ok {
+		if g.Variant != nil {
+			writeTag(&sb, "Variant", g.Variant.Name())
+		} else if g.Chess960 {
+			writeTag(&sb, "Variant", "Chess960")
 		}
+	}
 
-		// Convert move to SAN (Standard Algebraic Notation)
-		san := g.moveToSAN(result, i)
-		sb.WriteString(san)
-		sb.WriteString(" ")
+	var extra []string
+	for name := range g.Tags {
+		if !written[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		writeTag(&sb, name, g.Tags[name])
 	}
+	sb.WriteString("\n")
+
+	// 2. Movetext
+	root := g.moveTree()
+	w := &movetextWriter{
+		pgnLineWriter: pgnLineWriter{sb: &sb},
+		rootTurn:      replay.Turn,
+		rootFullmove:  replay.FullmoveNumber,
+	}
+	for _, token := range root.Annotation.pgnTokens() {
+		w.write(token)
+	}
+	if len(root.Children) > 0 {
+		w.writeLine(root.Children[0], 1, "")
+	}
+
+	// 3. Result terminator
+	w.write(result)
+	sb.WriteString("\n")
+
+	return sb.String()
+}
 
-	return strings.TrimSpace(sb.String())
+// writeTag writes one tag pair line, escaping quotes and backslashes
+func writeTag(sb *strings.Builder, name, value string) {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	sb.WriteString(fmt.Sprintf("[%s \"%s\"]\n", name, value))
 }
 
-// moveToSAN converts a move to Standard Algebraic Notation (e.g., "Nf3", "exd5", "O-O")
-// Note: This is a simplified SAN generator. Full disambiguation (e.g. Nbd7)
-// requires checking all other pieces, which we implement simply here.
-func (g *Game) moveToSAN(res MoveResult, moveIndex int) string {
-	m := res.Move
+// pgnLineWriter joins movetext tokens with spaces, starting a new line
+// whenever the next token would overflow pgnLineWidth
+type pgnLineWriter struct {
+	sb      *strings.Builder
+	lineLen int
+}
 
-	// 1. Castling
-	if m.MoveType == MoveCastling {
-		if m.To > m.From { // King side
-			return "O-O"
+func (w *pgnLineWriter) write(token string) {
+	if w.lineLen > 0 {
+		if w.lineLen+1+len(token) > pgnLineWidth {
+			w.sb.WriteString("\n")
+			w.lineLen = 0
+		} else {
+			w.sb.WriteString(" ")
+			w.lineLen++
 		}
-		return "O-O-O"
 	}
+	w.sb.WriteString(token)
+	w.lineLen += len(token)
+}
 
-	var sb strings.Builder
+// attach appends s to the previous token without a separating space
+func (w *pgnLineWriter) attach(s string) {
+	if w.lineLen+len(s) > pgnLineWidth {
+		w.sb.WriteString("\n")
+		w.lineLen = 0
+	}
+	w.sb.WriteString(s)
+	w.lineLen += len(s)
+}
+
+// movetextWriter writes a move tree as PGN movetext
+type movetextWriter struct {
+	pgnLineWriter
+	rootTurn     Color
+	rootFullmove int
+}
+
+// writeLine writes n, which is ply moves from the root, and the main line
+// that continues from it. Alternatives to a main line move follow it in
+// parentheses.
+func (w *movetextWriter) writeLine(n *MoveNode, ply int, prefix string) {
+	needNumber := true
+	for {
+		needNumber = w.writeMove(n, ply, needNumber, prefix)
+		prefix = ""
+
+		if parent := n.Parent; parent.Children[0] == n {
+			for _, v := range parent.Children[1:] {
+				w.writeLine(v, ply, "(")
+				w.attach(")")
+				needNumber = true
+			}
+		}
 
-	// 2. Piece Name (omitted for Pawns)
-	pieceChar := ""
-	switch m.Piece {
-	case Knight:
-		pieceChar = "N"
-	case Bishop:
-		pieceChar = "B"
-	case Rook:
-		pieceChar = "R"
-	case Queen:
-		pieceChar = "Q"
-	case King:
-		pieceChar = "K"
-	}
-	sb.WriteString(pieceChar)
-
-	// 3. Disambiguation (simplified)
-	// If a capture by pawn, we need the source file (e.g., "exd5")
-	if m.Piece == Pawn && res.WasCapture {
-		srcFile := IndexToCoord(m.From)[0:1]
-		sb.WriteString(srcFile)
-	}
-
-	// 4. Capture Indicator
-	if res.WasCapture {
-		sb.WriteString("x")
-	}
-
-	// 5. Destination
-	sb.WriteString(IndexToCoord(m.To))
-
-	// 6. Promotion
-	if res.WasPromotion {
-		sb.WriteString("=")
-		promChar := ""
-		switch m.Promotion {
-		case Queen:
-			promChar = "Q"
-		case Rook:
-			promChar = "R"
-		case Bishop:
-			promChar = "B"
-		case Knight:
-			promChar = "N"
+		if len(n.Children) == 0 {
+			return
 		}
-		sb.WriteString(promChar)
+		n = n.Children[0]
+		ply++
 	}
+}
 
-	// 7. Checks and Mates
-	if res.WasCheckmate {
-		sb.WriteString("#")
-	} else if res.WasCheck {
-		sb.WriteString("+")
+// writeMove writes one move and its annotation, and reports whether the next
+// move needs a number. Move numbers precede White's moves, and Black's moves
+// that open a line or follow a comment or variation.
+func (w *movetextWriter) writeMove(n *MoveNode, ply int, needNumber bool, prefix string) bool {
+	half := ply - 1 + int(w.rootTurn)
+	number := w.rootFullmove + half/2
+
+	token := n.SAN
+	if half%2 == 0 {
+		token = fmt.Sprintf("%d. %s", number, token)
+	} else if needNumber {
+		token = fmt.Sprintf("%d... %s", number, token)
 	}
+	w.write(prefix + token)
 
-	return sb.String()
+	tokens := n.Annotation.pgnTokens()
+	for _, t := range tokens {
+		w.write(t)
+	}
+	return len(tokens) > 0 && strings.HasSuffix(tokens[len(tokens)-1], "}")
+}
+
+// mainLineOutcome returns the outcome at the end of the main line: a result
+// declared there, or else the one the rules give in that position
+func (g *Game) mainLineOutcome() Outcome {
+	if g.Root == nil {
+		return g.Outcome()
+	}
+
+	end, last := g.Clone(), g.Root
+	for len(end.StateHistory) > 0 {
+		end.UndoMove()
+	}
+	for _, n := range g.MainLine() {
+		end.MakeMove(n.Move)
+		last = n
+	}
+	if last.declared.IsOver() {
+		return last.declared
+	}
+	return end.Outcome()
+}
+
+// rootPosition returns a copy of the game rewound to the position before the first move
+func (g *Game) rootPosition() *Game {
+	root := g.Clone()
+	for len(root.StateHistory) > 0 {
+		root.UndoMove()
+	}
+	return root.position()
 }
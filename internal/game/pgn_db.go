@@ -0,0 +1,242 @@
+package game
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// PGNFilter decides from a game's tags whether the game should be read
+type PGNFilter func(tags map[string]string) bool
+
+// TagEquals matches games whose tag has the given value (case-insensitive)
+func TagEquals(name, value string) PGNFilter {
+	return func(tags map[string]string) bool {
+		return strings.EqualFold(tags[name], value)
+	}
+}
+
+// PlayerFilter matches games in which a player whose name contains the
+// given text (case-insensitive) played either color
+func PlayerFilter(name string) PGNFilter {
+	name = strings.ToLower(name)
+	return func(tags map[string]string) bool {
+		return strings.Contains(strings.ToLower(tags["White"]), name) ||
+			strings.Contains(strings.ToLower(tags["Black"]), name)
+	}
+}
+
+// ResultFilter matches games with the given result token, e.g. "1-0"
+func ResultFilter(result string) PGNFilter {
+	return TagEquals("Result", result)
+}
+
+// ECOFilter matches games whose ECO code starts with the given prefix,
+// so "B" selects all semi-open games and "B90" a single opening
+func ECOFilter(prefix string) PGNFilter {
+	prefix = strings.ToUpper(prefix)
+	return func(tags map[string]string) bool {
+		return strings.HasPrefix(strings.ToUpper(tags["ECO"]), prefix)
+	}
+}
+
+// AllFilters matches games accepted by every filter
+func AllFilters(filters ...PGNFilter) PGNFilter {
+	return func(tags map[string]string) bool {
+		for _, f := range filters {
+			if !f(tags) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// PGNReader reads the games of a PGN database one at a time, so files with
+// thousands of games never have to be held in memory at once. Every game is
+// replayed through MakeMove and therefore legality-checked.
+type PGNReader struct {
+	r      *bufio.Reader
+	line   int    // Number of lines consumed so far
+	next   string // First line of the following game, already read
+	Filter PGNFilter
+}
+
+// NewPGNReader returns a reader over a PGN database
+func NewPGNReader(r io.Reader) *PGNReader {
+	return &PGNReader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next game accepted by Filter, or io.EOF once the database
+// is exhausted. A game that fails to parse is reported as a *PGNError with
+// line numbers relative to the whole file; calling Next again continues with
+// the following game. Games rejected by Filter are skipped without replaying
+// their moves.
+func (pr *PGNReader) Next() (*Game, error) {
+	for {
+		text, startLine, err := pr.readGameText()
+		if err != nil {
+			return nil, err
+		}
+
+		p := &pgnParser{lex: newPGNLexer(text)}
+		tags, err := p.parseTags()
+		if err != nil {
+			return nil, offsetPGNError(err, startLine)
+		}
+		if pr.Filter != nil && !pr.Filter(tags) {
+			continue
+		}
+
+		g, err := p.parseMovetext(tags)
+		if err != nil {
+			return nil, offsetPGNError(err, startLine)
+		}
+		if tok, err := p.next(); err != nil {
+			return nil, offsetPGNError(err, startLine)
+		} else if tok.kind != tokEOF {
+			return nil, offsetPGNError(p.lex.errorf(tok.line, tok.column, "unexpected content after the game result"), startLine)
+		}
+		return g, nil
+	}
+}
+
+// readGameText collects the lines of the next game. A game ends after its
+// result token, or where a tag pair line follows movetext. Braces only open
+// comments in movetext, so a "{" inside a tag value is harmless. Whatever
+// follows the result on its line starts the next game.
+func (pr *PGNReader) readGameText() (string, int, error) {
+	var sb strings.Builder
+	startLine := pr.line + 1
+	seenMovetext := false
+	var scan movetextScanner
+
+	for {
+		line := pr.next
+		if line != "" {
+			if sb.Len() == 0 {
+				startLine = pr.line
+			}
+			pr.next = ""
+		} else {
+			var err error
+			line, err = pr.r.ReadString('\n')
+			if line == "" && err != nil {
+				if err != io.EOF {
+					return "", 0, err
+				}
+				if strings.TrimSpace(sb.String()) == "" {
+					return "", 0, io.EOF
+				}
+				return sb.String(), startLine, nil
+			}
+			pr.line++
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if !scan.inComment && strings.HasPrefix(trimmed, "[") {
+			if seenMovetext {
+				pr.next = line
+				return sb.String(), startLine, nil
+			}
+			sb.WriteString(line)
+			continue
+		}
+
+		if sb.Len() == 0 && trimmed == "" {
+			startLine = pr.line + 1
+			continue
+		}
+		if !scan.inComment && strings.HasPrefix(trimmed, "%") {
+			sb.WriteString(line) // Escaped line
+			continue
+		}
+
+		if trimmed != "" {
+			seenMovetext = true
+		}
+		if end := scan.resultEnd(line); end >= 0 {
+			sb.WriteString(line[:end])
+			sb.WriteString("\n")
+			if rest := line[end:]; strings.TrimSpace(rest) != "" {
+				pr.next = rest
+			}
+			return sb.String(), startLine, nil
+		}
+		sb.WriteString(line)
+	}
+}
+
+// movetextScanner follows brace comments and variations across the lines
+// of a game's movetext to find the result token that ends it
+type movetextScanner struct {
+	inComment bool
+	depth     int // Variations open
+}
+
+// resultEnd returns the index just past the game's result token in line, or
+// -1 if the game does not end on this line
+func (s *movetextScanner) resultEnd(line string) int {
+	for i := 0; i < len(line); {
+		c := line[i]
+		switch {
+		case s.inComment:
+			s.inComment = c != '}'
+		case c == '{':
+			s.inComment = true
+		case c == ';':
+			return -1 // Rest-of-line comment
+		case c == '(':
+			s.depth++
+		case c == ')':
+			s.depth--
+		case !unicode.IsSpace(rune(c)):
+			word := i
+			for i < len(line) && !unicode.IsSpace(rune(line[i])) && !strings.ContainsRune("{}();", rune(line[i])) {
+				i++
+			}
+			if s.depth == 0 && isResultToken(line[word:i]) {
+				return i
+			}
+			continue
+		}
+		i++
+	}
+	return -1
+}
+
+// offsetPGNError converts a line number within one game to one within the file
+func offsetPGNError(err error, startLine int) error {
+	var pgnErr *PGNError
+	if errors.As(err, &pgnErr) {
+		shifted := *pgnErr
+		shifted.Line += startLine - 1
+		return &shifted
+	}
+	return err
+}
+
+// PGNWriter writes games to a PGN database, separated by blank lines
+type PGNWriter struct {
+	w     io.Writer
+	count int
+}
+
+// NewPGNWriter returns a writer that appends games to w
+func NewPGNWriter(w io.Writer) *PGNWriter {
+	return &PGNWriter{w: w}
+}
+
+// WriteGame writes one game in PGN export format
+func (pw *PGNWriter) WriteGame(g *Game) error {
+	if pw.count > 0 {
+		if _, err := io.WriteString(pw.w, "\n"); err != nil {
+			return err
+		}
+	}
+	pw.count++
+	_, err := io.WriteString(pw.w, g.GeneratePGN())
+	return err
+}
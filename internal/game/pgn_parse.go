@@ -0,0 +1,514 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// PGNError reports a problem in PGN text together with its position
+type PGNError struct {
+	Line   int
+	Column int
+	Msg    string
+	Err    error // Underlying error, e.g. ErrIllegalMove or a *FENError
+}
+
+func (e *PGNError) Error() string {
+	return fmt.Sprintf("pgn line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+func (e *PGNError) Unwrap() error {
+	return e.Err
+}
+
+// pgnTokenKind identifies the lexical tokens of PGN (see the PGN standard, section 7)
+type pgnTokenKind int
+
+const (
+	tokEOF pgnTokenKind = iota
+	tokSymbol
+	tokString
+	tokPeriod
+	tokLBracket
+	tokRBracket
+	tokLParen
+	tokRParen
+	tokComment
+	tokNAG
+	tokGlyph // Traditional suffix annotations such as "!" or "?!"
+)
+
+type pgnToken struct {
+	kind   pgnTokenKind
+	text   string
+	line   int
+	column int
+}
+
+// pgnLexer splits PGN text into tokens, tracking line and column numbers
+type pgnLexer struct {
+	src    []rune
+	pos    int
+	line   int
+	column int
+}
+
+func newPGNLexer(text string) *pgnLexer {
+	return &pgnLexer{src: []rune(text), line: 1, column: 1}
+}
+
+func (l *pgnLexer) peekRune() (rune, bool) {
+	if l.pos >= len(l.src) {
+		return 0, false
+	}
+	return l.src[l.pos], true
+}
+
+func (l *pgnLexer) readRune() rune {
+	r := l.src[l.pos]
+	l.pos++
+	if r == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+	return r
+}
+
+func (l *pgnLexer) errorf(line, column int, format string, args ...interface{}) error {
+	return &PGNError{Line: line, Column: column, Msg: fmt.Sprintf(format, args...)}
+}
+
+// isSymbolRune reports whether r may continue a PGN symbol token
+func isSymbolRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_+#=:-/@", r)
+}
+
+// next returns the next token, skipping whitespace and escaped lines
+func (l *pgnLexer) next() (pgnToken, error) {
+	for {
+		r, ok := l.peekRune()
+		if !ok {
+			return pgnToken{kind: tokEOF, line: l.line, column: l.column}, nil
+		}
+
+		// A '%' in the first column escapes the rest of the line
+		if r == '%' && l.column == 1 {
+			l.skipLine()
+			continue
+		}
+		if unicode.IsSpace(r) {
+			l.readRune()
+			continue
+		}
+		break
+	}
+
+	line, column := l.line, l.column
+	tok := pgnToken{line: line, column: column}
+	r := l.readRune()
+
+	switch {
+	case r == '[':
+		tok.kind = tokLBracket
+	case r == ']':
+		tok.kind = tokRBracket
+	case r == '(':
+		tok.kind = tokLParen
+	case r == ')':
+		tok.kind = tokRParen
+	case r == '.':
+		tok.kind = tokPeriod
+	case r == '*':
+		tok.kind, tok.text = tokSymbol, "*"
+	case r == '"':
+		text, err := l.readString(line, column)
+		if err != nil {
+			return tok, err
+		}
+		tok.kind, tok.text = tokString, text
+	case r == '{':
+		var sb strings.Builder
+		for {
+			c, ok := l.peekRune()
+			if !ok {
+				return tok, l.errorf(line, column, "comment is never closed")
+			}
+			l.readRune()
+			if c == '}' {
+				break
+			}
+			sb.WriteRune(c)
+		}
+		tok.kind, tok.text = tokComment, sb.String()
+	case r == ';':
+		start := l.pos
+		l.skipLine()
+		tok.kind, tok.text = tokComment, strings.TrimRight(string(l.src[start:l.pos]), "\r\n")
+	case r == '$':
+		var sb strings.Builder
+		for c, ok := l.peekRune(); ok && unicode.IsDigit(c); c, ok = l.peekRune() {
+			sb.WriteRune(l.readRune())
+		}
+		if sb.Len() == 0 {
+			return tok, l.errorf(line, column, "'$' must be followed by a number")
+		}
+		tok.kind, tok.text = tokNAG, sb.String()
+	case r == '!' || r == '?':
+		text := string(r)
+		for c, ok := l.peekRune(); ok && (c == '!' || c == '?'); c, ok = l.peekRune() {
+			text += string(l.readRune())
+		}
+		tok.kind, tok.text = tokGlyph, text
+	case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '@':
+		text := string(r)
+		for c, ok := l.peekRune(); ok && isSymbolRune(c); c, ok = l.peekRune() {
+			text += string(l.readRune())
+		}
+		tok.kind, tok.text = tokSymbol, text
+	default:
+		return tok, l.errorf(line, column, "unexpected character %q", r)
+	}
+
+	return tok, nil
+}
+
+// readString reads a quoted tag value, handling \" and \\ escapes
+func (l *pgnLexer) readString(line, column int) (string, error) {
+	var sb strings.Builder
+	for {
+		c, ok := l.peekRune()
+		if !ok || c == '\n' {
+			return "", l.errorf(line, column, "string is never closed")
+		}
+		l.readRune()
+		switch c {
+		case '"':
+			return sb.String(), nil
+		case '\\':
+			if esc, ok := l.peekRune(); ok && (esc == '"' || esc == '\\') {
+				sb.WriteRune(l.readRune())
+				continue
+			}
+		}
+		sb.WriteRune(c)
+	}
+}
+
+func (l *pgnLexer) skipLine() {
+	for r, ok := l.peekRune(); ok; r, ok = l.peekRune() {
+		l.readRune()
+		if r == '\n' {
+			return
+		}
+	}
+}
+
+// pgnParser builds a Game from PGN tokens
+type pgnParser struct {
+	lex    *pgnLexer
+	peeked *pgnToken
+}
+
+func (p *pgnParser) next() (pgnToken, error) {
+	if p.peeked != nil {
+		tok := *p.peeked
+		p.peeked = nil
+		return tok, nil
+	}
+	return p.lex.next()
+}
+
+func (p *pgnParser) peek() (pgnToken, error) {
+	if p.peeked == nil {
+		tok, err := p.lex.next()
+		if err != nil {
+			return tok, err
+		}
+		p.peeked = &tok
+	}
+	return *p.peeked, nil
+}
+
+func (p *pgnParser) expect(kind pgnTokenKind, what string) (pgnToken, error) {
+	tok, err := p.next()
+	if err != nil {
+		return tok, err
+	}
+	if tok.kind != kind {
+		return tok, p.lex.errorf(tok.line, tok.column, "expected %s", what)
+	}
+	return tok, nil
+}
+
+// isResultToken reports whether s is one of the four game termination markers
+func isResultToken(s string) bool {
+	return s == "1-0" || s == "0-1" || s == "1/2-1/2" || s == "*"
+}
+
+// parseResult converts a termination marker to a Result
+func parseResult(s string) Result {
+	switch s {
+	case "1-0":
+		return WhiteWins
+	case "0-1":
+		return BlackWins
+	case "1/2-1/2":
+		return Draw
+	}
+	return NoResult
+}
+
+// ParsePGN reads a single game from PGN text. The FEN tag is honoured when
+// present, every move is replayed through MakeMove so illegal moves are
+// rejected with their line and column, and the game result is applied.
+// Comments, NAGs and [%clk]/[%eval] commands are kept on the moves of the
+// move tree, and variations become sidelines in Game.Root.
+func ParsePGN(text string) (*Game, error) {
+	p := &pgnParser{lex: newPGNLexer(text)}
+
+	g, err := p.parseGame()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok, err := p.next(); err != nil {
+		return nil, err
+	} else if tok.kind != tokEOF {
+		return nil, p.lex.errorf(tok.line, tok.column, "unexpected content after the game result")
+	}
+	return g, nil
+}
+
+// parseGame reads the tag pairs and movetext of one game
+func (p *pgnParser) parseGame() (*Game, error) {
+	tags, err := p.parseTags()
+	if err != nil {
+		return nil, err
+	}
+	return p.parseMovetext(tags)
+}
+
+// parseTags reads the tag pair section of a game
+func (p *pgnParser) parseTags() (map[string]string, error) {
+	tags := map[string]string{}
+	var fenTok *pgnToken
+
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if tok.kind != tokLBracket {
+			// Validate the starting position now, while its position is
+			// known; the Variant tag may come after it
+			if fenTok != nil {
+				if err := newGameForTags(tags).LoadFEN(fenTok.text); err != nil {
+					return nil, &PGNError{Line: fenTok.line, Column: fenTok.column, Msg: err.Error(), Err: err}
+				}
+			}
+			return tags, nil
+		}
+		p.next()
+
+		name, err := p.expect(tokSymbol, "tag name")
+		if err != nil {
+			return nil, err
+		}
+		value, err := p.expect(tokString, "quoted tag value")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRBracket, "']' after tag value"); err != nil {
+			return nil, err
+		}
+		tags[name.text] = value.text
+		if name.text == "FEN" {
+			fenTok = &value
+		}
+	}
+}
+
+// newGameForTags returns a new game following the rules named by the
+// Variant tag: Chess960 or one of Variants
+func newGameForTags(tags map[string]string) *Game {
+	name := tags["Variant"]
+	switch variantKey(name) {
+	case "chess960", "fischerandom", "fischerrandom", "960":
+		g := NewGame()
+		g.Chess960 = true
+		return g
+	}
+	if v, ok := VariantByName(name); ok {
+		return NewVariantGame(v)
+	}
+	return NewGame()
+}
+
+// parseMovetext replays the movetext of a game whose tags have been read
+func (p *pgnParser) parseMovetext(tags map[string]string) (*Game, error) {
+	g := newGameForTags(tags)
+	g.Tags = tags
+	if fen, ok := tags["FEN"]; ok {
+		g.LoadFEN(fen)
+	}
+
+	// 2. Movetext
+	result, err := p.parseMoves(g, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	applyPGNResult(g, parseResult(result), g.Tags["Termination"])
+	return g, nil
+}
+
+// parseMoves plays moves up to the game result, or up to the ')' closing
+// the variation opened by open. Variations are added to the move tree and
+// the game is left at the end of the line it was parsing.
+func (p *pgnParser) parseMoves(g *Game, open *pgnToken) (string, error) {
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return "", err
+		}
+
+		switch tok.kind {
+		case tokEOF, tokLBracket:
+			if open != nil {
+				return "", p.lex.errorf(open.line, open.column, "variation is never closed")
+			}
+			// Missing termination marker; the next game (if any) starts here
+			return "*", nil
+		}
+		p.next()
+
+		switch tok.kind {
+		case tokPeriod:
+			// Move number indicators carry no moves
+		case tokComment:
+			g.CurrentNode().Annotation.addComment(tok.text)
+		case tokNAG, tokGlyph:
+			if err := p.addNAG(g, tok); err != nil {
+				return "", err
+			}
+		case tokLParen:
+			if err := p.parseVariation(g, tok); err != nil {
+				return "", err
+			}
+		case tokRParen:
+			if open == nil {
+				return "", p.lex.errorf(tok.line, tok.column, "')' without a matching '('")
+			}
+			return "", nil
+		case tokSymbol:
+			if isResultToken(tok.text) {
+				if open != nil {
+					// The game ends while the variation is still open
+					return "", p.lex.errorf(open.line, open.column, "variation is never closed")
+				}
+				return tok.text, nil
+			}
+			if isMoveNumber(tok.text) {
+				break
+			}
+			if err := p.playMove(g, tok); err != nil {
+				return "", err
+			}
+		default:
+			return "", p.lex.errorf(tok.line, tok.column, "unexpected %q in movetext", tok.text)
+		}
+	}
+}
+
+// parseVariation reads a variation replacing the last move played, then
+// returns to that move
+func (p *pgnParser) parseVariation(g *Game, open pgnToken) error {
+	node := g.CurrentNode()
+	if !g.Back() {
+		return p.lex.errorf(open.line, open.column, "variation before the first move")
+	}
+	if _, err := p.parseMoves(g, &open); err != nil {
+		return err
+	}
+	return g.GoTo(node)
+}
+
+// playMove plays a SAN move token, reporting failures at the token's position
+func (p *pgnParser) playMove(g *Game, tok pgnToken) error {
+	m, err := g.ParseSAN(tok.text)
+	if err != nil {
+		return &PGNError{
+			Line:   tok.line,
+			Column: tok.column,
+			Msg:    fmt.Sprintf("move %d%s %s: %v", g.FullmoveNumber, moveNumberDots(g.Turn), tok.text, err),
+			Err:    err,
+		}
+	}
+	g.MakeMove(m)
+	return nil
+}
+
+// addNAG attaches a "$n" or suffix glyph token to the last move
+func (p *pgnParser) addNAG(g *Game, tok pgnToken) error {
+	a := g.LastAnnotation()
+	if a == nil {
+		return p.lex.errorf(tok.line, tok.column, "annotation before the first move")
+	}
+
+	if tok.kind == tokGlyph {
+		nag, ok := glyphNAGs[tok.text]
+		if !ok {
+			return p.lex.errorf(tok.line, tok.column, "unknown annotation %q", tok.text)
+		}
+		a.AddNAG(nag)
+		return nil
+	}
+
+	nag, err := strconv.Atoi(tok.text)
+	if err != nil || nag > 255 {
+		return p.lex.errorf(tok.line, tok.column, "NAG $%s is out of range", tok.text)
+	}
+	a.AddNAG(nag)
+	return nil
+}
+
+// isMoveNumber reports whether s is a move number indicator such as "12"
+func isMoveNumber(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// moveNumberDots returns the separator used after a move number for the side to move
+func moveNumberDots(c Color) string {
+	if c == White {
+		return "."
+	}
+	return "..."
+}
+
+// applyPGNResult records a result the final position does not explain by
+// itself, such as a resignation or an agreed draw
+func applyPGNResult(g *Game, result Result, termination string) {
+	if result == NoResult || g.Outcome().IsOver() {
+		return
+	}
+
+	reason := TerminationResignation
+	switch {
+	case result == Draw && g.ClaimableDraw() != TerminationNone:
+		reason = g.ClaimableDraw()
+	case result == Draw:
+		reason = TerminationAgreement
+	case strings.EqualFold(termination, "time forfeit"):
+		reason = TerminationTimeout
+	}
+	g.declare(Outcome{Result: result, Termination: reason})
+}
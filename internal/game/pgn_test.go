@@ -0,0 +1,445 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+const operaGamePGN = `[Event "Casual game"]
+[Site "Paris FRA"]
+[Date "1858.??.??"]
+[Round "?"]
+[White "Paul Morphy"]
+[Black "Duke Karl / Count Isouard"]
+[Result "1-0"]
+
+1. e4 e5 2. Nf3 d6 3. d4 Bg4 {This is a weak move already.} 4. dxe5 Bxf3 5. Qxf3
+dxe5 6. Bc4 Nf6 7. Qb3 Qe7 8. Nc3 c6 9. Bg5 $1 b5 10. Nxb5! cxb5 (10... Qb4
+11. Qxb4) 11. Bxb5+ Nbd7 12. O-O-O Rd8 13. Rxd7 Rxd7 14. Rd1 Qe6 ; Forced
+15. Bxd7+ Nxd7 16. Qb8+ Nxb8 17. Rd8# 1-0
+`
+
+// Test importing a complete game with tags, comments, NAGs and a variation
+func TestParsePGN(t *testing.T) {
+	g, err := ParsePGN(operaGamePGN)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(g.History) != 33 {
+		t.Errorf("Expected 33 plies, got %d", len(g.History))
+	}
+	if g.Tags["White"] != "Paul Morphy" || g.Tags["Date"] != "1858.??.??" {
+		t.Errorf("Tags not imported: %v", g.Tags)
+	}
+	if want := "1n1Rkb1r/p4ppp/4q3/4p1B1/4P3/8/PPP2PPP/2K5 b k - 1 17"; g.FEN() != want {
+		t.Errorf("Expected final position %s, got %s", want, g.FEN())
+	}
+	if outcome := g.Outcome(); outcome.Result != WhiteWins || outcome.Termination != TerminationCheckmate {
+		t.Errorf("Expected 1-0 by checkmate, got %v", outcome)
+	}
+}
+
+// Test that a non-standard start and a result without checkmate are honoured
+func TestParsePGNSetUpAndResignation(t *testing.T) {
+	pgn := `[FEN "4k3/8/8/8/8/8/4P3/4K3 b - - 0 40"]
+[SetUp "1"]
+
+40... Kd7 41. e4 Kd6 0-1`
+
+	g, err := ParsePGN(pgn)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if g.FullmoveNumber != 42 || g.Turn != White {
+		t.Errorf("Expected white to move at move 42, got %s at %d", g.Turn, g.FullmoveNumber)
+	}
+	if outcome := g.Outcome(); outcome.Result != BlackWins || outcome.Termination != TerminationResignation {
+		t.Errorf("Expected 0-1 by resignation, got %v", outcome)
+	}
+}
+
+// Test that errors carry the position of the offending token
+func TestParsePGNErrors(t *testing.T) {
+	tests := []struct {
+		pgn          string
+		line, column int
+		err          error
+	}{
+		{"1. e4 e5\n2. Nf4 Nc6 *", 2, 4, ErrIllegalMove},
+		{"[White \"A\"]\n\n1. e4 e5 2. Ke3 *", 3, 13, ErrIllegalMove},
+		{"[White \"A\"\n1. e4 *", 2, 1, nil},
+		{"1. e4 { unterminated", 1, 7, nil},
+		{"1. e4 (1. d4 *", 1, 7, nil},
+		{"1. e4 e5 ) *", 1, 10, nil},
+		{"[FEN \"8/8/8/8/8/8/8/8 w - - 0 1\"]\n*", 1, 6, nil},
+	}
+
+	for _, tt := range tests {
+		_, err := ParsePGN(tt.pgn)
+		var pgnErr *PGNError
+		if !errors.As(err, &pgnErr) {
+			t.Errorf("%q: expected *PGNError, got %v", tt.pgn, err)
+			continue
+		}
+		if pgnErr.Line != tt.line || pgnErr.Column != tt.column {
+			t.Errorf("%q: expected error at %d:%d, got %v", tt.pgn, tt.line, tt.column, err)
+		}
+		if tt.err != nil && !errors.Is(err, tt.err) {
+			t.Errorf("%q: expected %v, got %v", tt.pgn, tt.err, err)
+		}
+	}
+}
+
+// Test that exported movetext imports back to the same game
+func TestPGNExportImportRoundTrip(t *testing.T) {
+	original, err := ParsePGN(operaGamePGN)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	imported, err := ParsePGN(original.GeneratePGN())
+	if err != nil {
+		t.Fatalf("Re-import failed: %v", err)
+	}
+	if imported.FEN() != original.FEN() || len(imported.History) != len(original.History) {
+		t.Errorf("Round trip changed the game: %s vs %s", imported.FEN(), original.FEN())
+	}
+}
+
+// Test the Seven Tag Roster, extra tag ordering, result and line wrapping
+func TestGeneratePGNExportFormat(t *testing.T) {
+	g, err := ParsePGN(operaGamePGN)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	g.Tags["Annotator"] = `Quoted "Name"`
+	delete(g.Tags, "Round")
+
+	pgn := g.GeneratePGN()
+	lines := strings.Split(strings.TrimRight(pgn, "\n"), "\n")
+
+	wantTags := []string{
+		`[Event "Casual game"]`,
+		`[Site "Paris FRA"]`,
+		`[Date "1858.??.??"]`,
+		`[Round "?"]`,
+		`[White "Paul Morphy"]`,
+		`[Black "Duke Karl / Count Isouard"]`,
+		`[Result "1-0"]`,
+		`[Annotator "Quoted \"Name\""]`,
+		``,
+	}
+	for i, want := range wantTags {
+		if lines[i] != want {
+			t.Errorf("Line %d: expected %s, got %s", i+1, want, lines[i])
+		}
+	}
+
+	for _, line := range lines {
+		if len(line) > pgnLineWidth {
+			t.Errorf("Line exceeds %d characters: %q", pgnLineWidth, line)
+		}
+	}
+	if !strings.HasSuffix(pgn, " 1-0\n") {
+		t.Errorf("PGN should end with the result terminator:\n%s", pgn)
+	}
+
+	imported, err := ParsePGN(pgn)
+	if err != nil {
+		t.Fatalf("Exported PGN does not import: %v", err)
+	}
+	if imported.Tags["Annotator"] != `Quoted "Name"` {
+		t.Errorf("Escaped tag value did not round trip: %q", imported.Tags["Annotator"])
+	}
+}
+
+// Test SetUp/FEN tags and move numbering for a game starting with Black to move
+func TestGeneratePGNFromPosition(t *testing.T) {
+	g := NewGame()
+	g.LoadFEN("4k3/8/8/8/8/8/4P3/4K3 b - - 0 40")
+	for _, san := range []string{"Kd7", "e4", "Kd6"} {
+		m, err := g.ParseSAN(san)
+		if err != nil {
+			t.Fatalf("%s: %v", san, err)
+		}
+		g.MakeMove(m)
+	}
+
+	pgn := g.GeneratePGN()
+	for _, want := range []string{
+		"[SetUp \"1\"]\n[FEN \"4k3/8/8/8/8/8/4P3/4K3 b - - 0 40\"]\n",
+		"\n40... Kd7 41. e4 Kd6 *\n",
+	} {
+		if !strings.Contains(pgn, want) {
+			t.Errorf("PGN missing %q:\n%s", want, pgn)
+		}
+	}
+	if strings.Contains(NewGame().GeneratePGN(), "FEN") {
+		t.Error("Standard start position should not write a FEN tag")
+	}
+}
+
+// Test that comments, NAGs and embedded commands are attached to moves
+func TestPGNAnnotations(t *testing.T) {
+	g, err := ParsePGN(operaGamePGN)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	line := g.MainLine()
+	if len(line) != len(g.History) {
+		t.Fatalf("Expected a main line of %d moves, got %d", len(g.History), len(line))
+	}
+	if c := line[5].Annotation.Comment; c != "This is a weak move already." {
+		t.Errorf("Expected comment on 3... Bg4, got %q", c)
+	}
+	if nags := line[16].Annotation.NAGs; len(nags) != 1 || nags[0] != NAGGoodMove {
+		t.Errorf("Expected $1 on 9. Bg5, got %v", nags)
+	}
+	if nags := line[18].Annotation.NAGs; len(nags) != 1 || nags[0] != NAGGoodMove {
+		t.Errorf("Expected ! on 10. Nxb5 as $1, got %v", nags)
+	}
+	if c := line[27].Annotation.Comment; c != "Forced" {
+		t.Errorf("Expected rest-of-line comment on 14... Qe6, got %q", c)
+	}
+
+	g, err = ParsePGN(`{Blitz game} 1. e4 {[%clk 0:04:58.5] [%eval 0.3] Best by test} e5 ?! {[%clk 0:04:59]} *`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c := g.Root.Annotation.Comment; c != "Blitz game" {
+		t.Errorf("Expected game comment, got %q", c)
+	}
+	line = g.MainLine()
+	first := line[0].Annotation
+	if clk, ok := first.Clock(); !ok || clk != 4*time.Minute+58500*time.Millisecond {
+		t.Errorf("Expected clock 4m58.5s, got %v (%v)", clk, ok)
+	}
+	if eval, ok := first.Eval(); !ok || eval != "0.3" {
+		t.Errorf("Expected eval 0.3, got %q (%v)", eval, ok)
+	}
+	if first.Comment != "Best by test" {
+		t.Errorf("Expected commands stripped from comment, got %q", first.Comment)
+	}
+	if nags := line[1].Annotation.NAGs; len(nags) != 1 || nags[0] != NAGDubiousMove {
+		t.Errorf("Expected ?! as $6, got %v", nags)
+	}
+
+	if _, err := ParsePGN("$1 1. e4 *"); err == nil {
+		t.Error("Expected error for a NAG before the first move")
+	}
+}
+
+// Test that annotations survive export and re-import
+func TestPGNAnnotationsRoundTrip(t *testing.T) {
+	g := NewGame()
+	g.Root.Annotation.Comment = "Annotated"
+	for _, san := range []string{"e4", "e5", "Nf3"} {
+		m, err := g.ParseSAN(san)
+		if err != nil {
+			t.Fatalf("ParseSAN(%q): %v", san, err)
+		}
+		g.MakeMove(m)
+	}
+	first := &g.MainLine()[0].Annotation
+	first.AddNAG(NAGGoodMove)
+	first.SetClock(3*time.Minute + 2*time.Second)
+	first.SetEval(0.25)
+	first.Comment = "King's pawn"
+	g.LastAnnotation().AddNAG(NAGSpeculativeMove)
+
+	pgn := g.GeneratePGN()
+	want := `{Annotated} 1. e4 $1 {[%clk 0:03:02] [%eval 0.25] King's pawn} 1... e5 2. Nf3 $5 *`
+	if !strings.Contains(strings.Join(strings.Fields(pgn), " "), want) {
+		t.Errorf("Expected movetext %q in:\n%s", want, pgn)
+	}
+
+	imported, err := ParsePGN(pgn)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if imported.Root.Annotation.Comment != "Annotated" || len(imported.MainLine()) != 3 {
+		t.Fatalf("Annotations not imported:\n%s", imported.GeneratePGN())
+	}
+	if imported.GeneratePGN() != pgn {
+		t.Errorf("Round trip changed the PGN:\n%s", imported.GeneratePGN())
+	}
+
+	g.UndoMove()
+	if line := g.MainLine(); len(line) != 2 {
+		t.Errorf("Expected UndoMove to drop the move from the tree, got %d moves", len(line))
+	}
+}
+
+const pgnDatabase = `[Event "Game one"]
+[White "Morphy"]
+[Black "Anderssen"]
+[Result "1-0"]
+[ECO "C41"]
+
+1. e4 e5 2. Nf3 d6 { a comment
+[that spans lines] } 3. d4 1-0
+
+[Event "Game two"]
+[White "Fischer"]
+[Black "Spassky"]
+[Result "1/2-1/2"]
+[ECO "B90"]
+
+1. e4 c5 2. Nf3 d6 1/2-1/2
+
+[Event "Broken"]
+[White "Spassky"]
+[Black "Fischer"]
+[Result "0-1"]
+
+1. d4 Nf6 2. Nf4 0-1
+
+[Event "Game four"]
+[White "Tal"]
+[Black "Fischer"]
+[Result "0-1"]
+[ECO "B20"]
+
+1. e4 c5 0-1
+`
+
+// Test streaming a database, including recovery after a bad game
+func TestPGNReader(t *testing.T) {
+	r := NewPGNReader(strings.NewReader(pgnDatabase))
+
+	var events []string
+	var brokenErr error
+	for {
+		g, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			brokenErr = err
+			continue
+		}
+		events = append(events, g.Tags["Event"])
+	}
+
+	if strings.Join(events, ",") != "Game one,Game two,Game four" {
+		t.Errorf("Unexpected games read: %v", events)
+	}
+
+	var pgnErr *PGNError
+	if !errors.As(brokenErr, &pgnErr) || pgnErr.Line != 23 || pgnErr.Column != 14 {
+		t.Errorf("Expected error at file line 23, column 14, got %v", brokenErr)
+	}
+}
+
+// Test that games are split at their result, whatever braces tag values
+// hold and whether or not the games have tag pairs
+func TestPGNReaderSplitsGames(t *testing.T) {
+	const db = `[Event "One"]
+[Annotator "{x"]
+
+1. e4 e5 1-0
+
+[Event "Two"]
+
+1. d4 { a (comment) 0-1 } d5 (1... Nf6) 0-1
+1. c4 *
+1. Nf3 1/2-1/2 1. g3 1-0
+`
+	r := NewPGNReader(strings.NewReader(db))
+	var games []string
+	for {
+		g, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		games = append(games, fmt.Sprintf("%s:%d:%s", g.Tags["Event"], len(g.History), g.Outcome().Result))
+	}
+	want := "One:2:1-0,Two:2:0-1,:1:*,:1:1/2-1/2,:1:1-0"
+	if got := strings.Join(games, ","); got != want {
+		t.Errorf("Games read: %s, want %s", got, want)
+	}
+}
+
+// Test tag filters
+func TestPGNReaderFilters(t *testing.T) {
+	tests := []struct {
+		filter PGNFilter
+		want   string
+	}{
+		{PlayerFilter("fischer"), "Game two,Game four"},
+		{PlayerFilter("Tal"), "Game four"},
+		{ResultFilter("0-1"), "Game four"},
+		{ECOFilter("B"), "Game two,Game four"},
+		{AllFilters(PlayerFilter("Fischer"), ECOFilter("B9")), "Game two"},
+		{TagEquals("White", "morphy"), "Game one"},
+	}
+
+	for _, tt := range tests {
+		r := NewPGNReader(strings.NewReader(pgnDatabase))
+		r.Filter = tt.filter
+
+		var events []string
+		for {
+			g, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				continue // The broken game is reported and skipped
+			}
+			events = append(events, g.Tags["Event"])
+		}
+		if got := strings.Join(events, ","); got != tt.want {
+			t.Errorf("Expected %s, got %s", tt.want, got)
+		}
+	}
+}
+
+// Test that written databases read back game for game
+func TestPGNWriter(t *testing.T) {
+	var sb strings.Builder
+	w := NewPGNWriter(&sb)
+
+	r := NewPGNReader(strings.NewReader(pgnDatabase))
+	r.Filter = func(tags map[string]string) bool { return tags["ECO"] != "" }
+	var fens []string
+	for {
+		g, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		fens = append(fens, g.FEN())
+		if err := w.WriteGame(g); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	r = NewPGNReader(strings.NewReader(sb.String()))
+	for i := 0; ; i++ {
+		g, err := r.Next()
+		if err == io.EOF {
+			if i != len(fens) {
+				t.Errorf("Expected %d games, read back %d", len(fens), i)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatalf("Re-read failed: %v", err)
+		}
+		if g.FEN() != fens[i] {
+			t.Errorf("Game %d: expected %s, got %s", i+1, fens[i], g.FEN())
+		}
+	}
+}
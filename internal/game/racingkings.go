@@ -0,0 +1,71 @@
+package game
+
+// RacingKings is a race to the eighth rank. Nobody may give check, so
+// neither king is ever in check. If White's king gets there first, Black
+// may still draw by reaching it on the next move.
+type RacingKings struct{ StandardRules }
+
+// rank8 holds the goal squares
+const rank8 = Bitboard(0xFF) << 56
+
+func (RacingKings) Name() string     { return "Racing Kings" }
+func (RacingKings) StartFEN() string { return "8/8/8/8/8/8/krbnNBRK/qrbnNBRQ w - - 0 1" }
+
+// InsufficientMaterial is never true: the kings can always race
+func (RacingKings) InsufficientMaterial(g *Game) bool { return false }
+
+// CanWin is always true, since either king can reach the eighth rank
+func (RacingKings) CanWin(g *Game, c Color) bool { return true }
+
+// FilterMoves drops the moves that give check, and all moves once the race
+// is over
+func (v RacingKings) FilterMoves(g *Game, moves []Move) []Move {
+	if v.Outcome(g).IsOver() {
+		return nil
+	}
+	bb := &g.bb
+	legal := moves[:0]
+	for _, m := range moves {
+		after := *bb
+		after.play(g, m)
+		if !after.inCheck(g.Turn.Opponent()) {
+			legal = append(legal, m)
+		}
+	}
+	return legal
+}
+
+func (RacingKings) Outcome(g *Game) Outcome {
+	bb := &g.bb
+	white := bb.pieces[White][King]&rank8 != 0
+	black := bb.pieces[Black][King]&rank8 != 0
+	switch {
+	case white && black:
+		return Outcome{Result: Draw, Termination: TerminationRacingKings}
+	case black:
+		return Outcome{Result: BlackWins, Termination: TerminationRacingKings}
+	case white && (g.Turn == White || !blackCanReachGoal(g, bb)):
+		return Outcome{Result: WhiteWins, Termination: TerminationRacingKings}
+	}
+	return Outcome{}
+}
+
+// blackCanReachGoal reports whether Black, to move after White reached the
+// eighth rank, has a legal king move onto it
+func blackCanReachGoal(g *Game, bb *bitboards) bool {
+	kings := bb.pieces[Black][King]
+	if kings == 0 {
+		return false
+	}
+	king := kings.LSB()
+	targets := kingAttacks[king] & rank8 &^ bb.colors[Black]
+	for targets != 0 {
+		m := Move{From: king, To: targets.PopLSB(), Piece: King}
+		after := *bb
+		after.play(g, m)
+		if !after.inCheck(Black) && !after.inCheck(White) {
+			return true
+		}
+	}
+	return false
+}
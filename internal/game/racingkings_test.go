@@ -0,0 +1,73 @@
+package game
+
+import "testing"
+
+func TestRacingKingsPerft(t *testing.T) {
+	tests := []struct {
+		fen    string
+		counts []uint64
+	}{
+		{RacingKings{}.StartFEN(), []uint64{21, 421, 11264}},
+		{"4brn1/2K2k2/8/8/8/8/8/8 w - - 0 1", []uint64{6, 33, 178, 3151, 12981}},
+	}
+	for _, tt := range tests {
+		g := NewVariantGame(RacingKings{})
+		if err := g.LoadFEN(tt.fen); err != nil {
+			t.Fatal(err)
+		}
+		for i, want := range tt.counts {
+			if testing.Short() && want > 10000 {
+				break
+			}
+			if got := g.Perft(i + 1); got != want {
+				t.Errorf("%s: perft(%d) = %d, want %d", tt.fen, i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestRacingKingsRace(t *testing.T) {
+	g := NewVariantGame(RacingKings{})
+
+	// Giving check is illegal
+	if err := g.LoadFEN("8/8/8/8/8/1k6/8/K6R w - - 0 1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.ParseSAN("Rh3"); err == nil {
+		t.Error("Rh3 gives check")
+	}
+
+	// White gets there first, but Black can follow and draw
+	if err := g.LoadFEN("8/1K5k/8/8/8/8/8/8 w - - 0 1"); err != nil {
+		t.Fatal(err)
+	}
+	m, err := g.ParseSAN("Kb8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.MakeMove(m)
+	if o := g.Outcome(); o.IsOver() {
+		t.Fatalf("Black may still reach the eighth rank: %v", o)
+	}
+	m, err = g.ParseSAN("Kh8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.MakeMove(m)
+	if o := g.Outcome(); o.Result != Draw || o.Termination != TerminationRacingKings {
+		t.Errorf("Outcome = %v, want a draw", o)
+	}
+
+	// Black cannot follow
+	if err := g.LoadFEN("8/1K6/7k/8/8/8/8/8 w - - 0 1"); err != nil {
+		t.Fatal(err)
+	}
+	m, err = g.ParseSAN("Kb8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.MakeMove(m)
+	if o := g.Outcome(); o.Result != WhiteWins {
+		t.Errorf("Outcome = %v, want White to win", o)
+	}
+}
@@ -0,0 +1,274 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Errors returned (wrapped) when a move cannot be matched to a legal move
+var (
+	ErrIllegalMove   = errors.New("illegal move")
+	ErrAmbiguousMove = errors.New("ambiguous move")
+)
+
+// pieceLetter returns the SAN letter for a piece type ("" for pawns)
+func pieceLetter(p PieceType) string {
+	switch p {
+	case Knight:
+		return "N"
+	case Bishop:
+		return "B"
+	case Rook:
+		return "R"
+	case Queen:
+		return "Q"
+	case King:
+		return "K"
+	}
+	return ""
+}
+
+// MoveToSAN converts a legal move in the current position to Standard
+// Algebraic Notation (e.g. "Nf3", "exd5", "Nbd7", "R1e2", "e8=Q+", "O-O",
+// or a drop such as "N@f3").
+// Disambiguation only considers other legal moves, so a pinned piece never
+// forces a file or rank to be added.
+func (g *Game) MoveToSAN(m Move) string {
+	var sb strings.Builder
+	piece := g.Board[m.From].Type
+
+	if m.MoveType == MoveDrop {
+		sb.WriteString(dropSAN(m))
+	} else if m.MoveType == MoveCastling {
+		// 1. Castling
+		if m.To%8 == 6 { // King lands on the g-file
+			sb.WriteString("O-O")
+		} else {
+			sb.WriteString("O-O-O")
+		}
+	} else {
+		isCapture := g.Board[m.To].Type != Empty || m.MoveType == MoveEnPassant
+
+		// 2. Piece letter, or source file for pawn captures
+		if piece == Pawn {
+			if isCapture {
+				sb.WriteByte(IndexToCoord(m.From)[0])
+			}
+		} else {
+			sb.WriteString(pieceLetter(piece))
+			sb.WriteString(g.disambiguation(m, piece))
+		}
+
+		// 3. Capture, destination and promotion
+		if isCapture {
+			sb.WriteString("x")
+		}
+		sb.WriteString(IndexToCoord(m.To))
+		if m.Promotion != Empty {
+			sb.WriteString("=")
+			sb.WriteString(pieceLetter(m.Promotion))
+		}
+	}
+
+	// 4. Check or checkmate
+	snapshot := g.playMove(m)
+	if g.InCheck() {
+		if len(g.GenerateLegalMoves()) == 0 {
+			sb.WriteString("#")
+		} else {
+			sb.WriteString("+")
+		}
+	}
+	g.retractMove(snapshot)
+
+	return sb.String()
+}
+
+// disambiguation returns the source file, rank or square needed to tell m
+// apart from other legal moves of the same piece type to the same square
+func (g *Game) disambiguation(m Move, piece PieceType) string {
+	ambiguous, sameFile, sameRank := false, false, false
+
+	for _, other := range g.GenerateLegalMoves() {
+		if other.To != m.To || other.From == m.From || g.Board[other.From].Type != piece {
+			continue
+		}
+		ambiguous = true
+		if other.From%8 == m.From%8 {
+			sameFile = true
+		}
+		if other.From/8 == m.From/8 {
+			sameRank = true
+		}
+	}
+
+	from := IndexToCoord(m.From)
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return from[:1]
+	case !sameRank:
+		return from[1:]
+	default:
+		return from
+	}
+}
+
+// position returns a copy of the current position without any move history
+func (g *Game) position() *Game {
+	return &Game{
+		Board:           g.Board,
+		bb:              g.bb,
+		Turn:            g.Turn,
+		Castling:        g.Castling,
+		Chess960:        g.Chess960,
+		Variant:         g.Variant,
+		Checks:          g.Checks,
+		Pockets:         g.Pockets,
+		promoted:        g.promoted,
+		castlingRooks:   g.castlingRooks,
+		EnPassantTarget: g.EnPassantTarget,
+		HalfmoveClock:   g.HalfmoveClock,
+		FullmoveNumber:  g.FullmoveNumber,
+		hash:            g.hash,
+	}
+}
+
+// sanPattern matches piece moves, pawn moves and promotions, e.g. "Nbd7", "exd5", "e8=Q"
+var sanPattern = regexp.MustCompile(`^([NBRQK])?([a-h])?([1-8])?(x|:)?([a-h][1-8])(?:=?([NBRQKnbrqk]))?(?:e\.p\.)?$`)
+
+// dropPattern matches drops such as "N@f3", "P@e4" or "@e4"
+var dropPattern = regexp.MustCompile(`^([PNBRQ])?@([a-h][1-8])$`)
+
+// uciPattern matches coordinate moves such as "e2e4" or "a7a8q"
+var uciPattern = regexp.MustCompile(`^[a-h][1-8][a-h][1-8][qrbnQRBN]?$`)
+
+// commonerUCIPattern also matches promotions to king, such as "a7a8k", for
+// variants where the king is an ordinary piece
+var commonerUCIPattern = regexp.MustCompile(`^[a-h][1-8][a-h][1-8][qrbnkQRBNK]?$`)
+
+// ParseSAN matches a move in Standard Algebraic Notation against the legal
+// moves of the current position. Check and mate markers and annotation
+// glyphs ("!", "?!", ...) are ignored. Errors wrap ErrIllegalMove or
+// ErrAmbiguousMove.
+func (g *Game) ParseSAN(san string) (Move, error) {
+	clean := strings.TrimSpace(san)
+	clean = strings.TrimRight(clean, "!?")
+	clean = strings.TrimRight(clean, "+#")
+	if clean == "" {
+		return Move{}, fmt.Errorf("empty move")
+	}
+
+	legalMoves := g.GenerateLegalMoves()
+
+	// 1. Castling
+	switch clean {
+	case "O-O", "0-0", "O-O-O", "0-0-0":
+		kingSide := len(clean) == 3
+		for _, m := range legalMoves {
+			if m.MoveType == MoveCastling && (m.To%8 == 6) == kingSide {
+				return m, nil
+			}
+		}
+		return Move{}, fmt.Errorf("%w: %s cannot castle %s here", ErrIllegalMove, g.Turn, castlingSideName(kingSide))
+	}
+
+	// 2. Drops
+	if parts := dropPattern.FindStringSubmatch(clean); parts != nil {
+		return g.parseDrop(parts[1], parts[2], legalMoves)
+	}
+
+	// 3. Piece and pawn moves
+	parts := sanPattern.FindStringSubmatch(clean)
+	if parts == nil {
+		return Move{}, fmt.Errorf("cannot read %q as a move; use SAN like 'Nf3' or UCI like 'g1f3'", san)
+	}
+
+	piece := Pawn
+	if parts[1] != "" {
+		piece = charToPiece(rune(parts[1][0])).Type
+	}
+	fromFile, fromRank := -1, -1
+	if parts[2] != "" {
+		fromFile = int(parts[2][0] - 'a')
+	}
+	if parts[3] != "" {
+		fromRank = int(parts[3][0] - '1')
+	}
+	to := CoordToIndex(parts[5])
+	promotion := Empty
+	if parts[6] != "" {
+		promotion = charToPiece(rune(strings.ToUpper(parts[6])[0])).Type
+	}
+
+	var candidates []Move
+	for _, m := range legalMoves {
+		if m.To != to || m.MoveType == MoveCastling || g.Board[m.From].Type != piece {
+			continue
+		}
+		if (fromFile >= 0 && m.From%8 != fromFile) || (fromRank >= 0 && m.From/8 != fromRank) {
+			continue
+		}
+		if m.Promotion != Empty && promotion == Empty {
+			return Move{}, fmt.Errorf("%w: %s needs a promotion piece, e.g. %s=Q", ErrIllegalMove, clean, clean)
+		}
+		if m.Promotion != promotion {
+			continue
+		}
+		candidates = append(candidates, m)
+	}
+
+	switch len(candidates) {
+	case 0:
+		return Move{}, fmt.Errorf("%w: no %s %s can move to %s", ErrIllegalMove, g.Turn, strings.ToLower(piece.String()), parts[5])
+	case 1:
+		return candidates[0], nil
+	}
+
+	options := make([]string, len(candidates))
+	for i, m := range candidates {
+		options[i] = g.MoveToSAN(m)
+	}
+	return Move{}, fmt.Errorf("%w: %s could be %s", ErrAmbiguousMove, clean, strings.Join(options, " or "))
+}
+
+// ParseMoveInput accepts a move typed by a player, either as UCI coordinates
+// ("g1f3", "e7e8q") or as SAN ("Nf3", "e8=Q+"); drops read "N@f3" in both.
+// Castling may be given as the king taking its own rook ("e1h1"), which is
+// the only UCI form of castling in Chess960.
+func (g *Game) ParseMoveInput(input string) (Move, error) {
+	clean := strings.TrimSpace(input)
+	pattern := uciPattern
+	if !g.rules().RoyalKing() {
+		pattern = commonerUCIPattern
+	}
+	if !pattern.MatchString(clean) {
+		return g.ParseSAN(clean)
+	}
+
+	legalMoves := g.GenerateLegalMoves()
+	if m, ok := g.castlingByRook(CoordToIndex(clean[:2]), CoordToIndex(clean[2:4]), legalMoves); ok {
+		return m, nil
+	}
+	if g.Chess960 {
+		// A king move onto the g- or c-file is then a plain king move
+		moves := make([]Move, 0, len(legalMoves))
+		for _, m := range legalMoves {
+			if m.MoveType != MoveCastling {
+				moves = append(moves, m)
+			}
+		}
+		legalMoves = moves
+	}
+	return ParseMove(clean, legalMoves)
+}
+
+func castlingSideName(kingSide bool) string {
+	if kingSide {
+		return "kingside"
+	}
+	return "queenside"
+}
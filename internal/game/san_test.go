@@ -0,0 +1,206 @@
+package game
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// sanReferenceGames are complete games in SAN taken from published scores
+var sanReferenceGames = []struct {
+	name  string
+	moves string
+}{
+	{
+		// Morphy vs Duke Karl / Count Isouard, Paris 1858
+		"Opera Game",
+		"e4 e5 Nf3 d6 d4 Bg4 dxe5 Bxf3 Qxf3 dxe5 Bc4 Nf6 Qb3 Qe7 Nc3 c6 Bg5 b5 Nxb5 cxb5 " +
+			"Bxb5+ Nbd7 O-O-O Rd8 Rxd7 Rxd7 Rd1 Qe6 Bxd7+ Nxd7 Qb8+ Nxb8 Rd8#",
+	},
+	{
+		// Byrne vs Fischer, New York 1956
+		"Game of the Century",
+		"Nf3 Nf6 c4 g6 Nc3 Bg7 d4 O-O Bf4 d5 Qb3 dxc4 Qxc4 c6 e4 Nbd7 Rd1 Nb6 Qc5 Bg4 " +
+			"Bg5 Na4 Qa3 Nxc3 bxc3 Nxe4 Bxe7 Qb6 Bc4 Nxc3 Bc5 Rfe8+ Kf1 Be6 Bxb6 Bxc4+ " +
+			"Kg1 Ne2+ Kf1 Nxd4+ Kg1 Ne2+ Kf1 Nc3+ Kg1 axb6 Qb4 Ra4 Qxb6 Nxd1 h3 Rxa2 Kh2 " +
+			"Nxf2 Re1 Rxe1 Qd8+ Bf8 Nxe1 Bd5 Nf3 Ne4 Qb8 b5 h4 h5 Ne5 Kg7 Kg1 Bc5+ Kf1 " +
+			"Ng3+ Ke1 Bb4+ Kd1 Bb3+ Kc1 Ne2+ Kb1 Nc3+ Kc1 Rc2#",
+	},
+	{
+		// Anderssen vs Kieseritzky, London 1851
+		"Immortal Game",
+		"e4 e5 f4 exf4 Bc4 Qh4+ Kf1 b5 Bxb5 Nf6 Nf3 Qh6 d3 Nh5 Nh4 Qg5 Nf5 c6 g4 Nf6 " +
+			"Rg1 cxb5 h4 Qg6 h5 Qg5 Qf3 Ng8 Bxf4 Qf6 Nc3 Bc5 Nd5 Qxb2 Bd6 Bxg1 e5 Qxa1+ " +
+			"Ke2 Na6 Nxg7+ Kd8 Qf6+ Nxf6 Be7#",
+	},
+}
+
+// findSAN returns the legal move whose SAN is san
+func findSAN(g *Game, san string) (Move, bool) {
+	for _, m := range g.GenerateLegalMoves() {
+		if g.MoveToSAN(m) == san {
+			return m, true
+		}
+	}
+	return Move{}, false
+}
+
+// Test that replaying reference games reproduces their published SAN and
+// that every legal move in every position gets a distinct SAN string
+func TestSANReferenceGames(t *testing.T) {
+	for _, game := range sanReferenceGames {
+		g := NewGame()
+
+		for ply, san := range strings.Fields(game.moves) {
+			seen := map[string]bool{}
+			for _, m := range g.GenerateLegalMoves() {
+				s := g.MoveToSAN(m)
+				if seen[s] {
+					t.Fatalf("%s ply %d: SAN %s is ambiguous in %s", game.name, ply+1, s, g.FEN())
+				}
+				seen[s] = true
+			}
+
+			m, ok := findSAN(g, san)
+			if !ok {
+				t.Fatalf("%s ply %d: no legal move generates %s in %s", game.name, ply+1, san, g.FEN())
+			}
+			g.MakeMove(m)
+		}
+	}
+}
+
+// Test disambiguation by file, rank and full square, and that pinned pieces are ignored
+func TestSANDisambiguation(t *testing.T) {
+	tests := []struct {
+		fen      string
+		from, to string
+		want     string
+	}{
+		{"7k/8/8/8/8/4R3/8/4RK2 w - - 0 1", "e1", "e2", "R1e2"},
+		{"7k/8/8/8/8/4R3/8/4RK2 w - - 0 1", "e3", "e2", "R3e2"},
+		{"4k3/8/8/8/8/8/8/R4RK1 w - - 0 1", "a1", "d1", "Rad1"},
+		{"8/k7/8/8/4Q2Q/8/2K5/4Q3 w - - 0 1", "e4", "h1", "Qe4h1"},
+		{"8/k7/8/8/4Q2Q/8/2K5/4Q3 w - - 0 1", "e1", "h1", "Q1h1"},
+		{"8/k7/8/8/4Q2Q/8/2K5/4Q3 w - - 0 1", "h4", "h1", "Qhh1"},
+		{"4k3/8/8/8/1b6/8/3N4/N3K3 w - - 0 1", "a1", "b3", "Nb3"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5", "d6", "exd6"},
+		{"8/4P3/8/8/8/8/k7/4K3 w - - 0 1", "e7", "e8", "e8=Q"},
+		{"2k5/4P3/8/8/8/8/8/4K3 w - - 0 1", "e7", "e8", "e8=Q+"},
+		{"6k1/5ppp/8/8/8/8/8/R3K3 w Q - 0 1", "a1", "a8", "Ra8#"},
+		{"6k1/8/8/8/8/8/8/R3K2R w KQ - 0 1", "e1", "g1", "O-O"},
+		{"6k1/8/8/8/8/8/8/R3K2R w KQ - 0 1", "e1", "c1", "O-O-O"},
+	}
+
+	for _, tt := range tests {
+		g := NewGame()
+		if err := g.LoadFEN(tt.fen); err != nil {
+			t.Fatalf("%s: %v", tt.fen, err)
+		}
+
+		found := false
+		for _, m := range g.GenerateLegalMoves() {
+			if m.From != CoordToIndex(tt.from) || m.To != CoordToIndex(tt.to) {
+				continue
+			}
+			if m.Promotion != Empty && m.Promotion != Queen {
+				continue
+			}
+			found = true
+			if got := g.MoveToSAN(m); got != tt.want {
+				t.Errorf("%s %s%s: expected %s, got %s", tt.fen, tt.from, tt.to, tt.want, got)
+			}
+		}
+		if !found {
+			t.Errorf("%s: %s%s is not legal", tt.fen, tt.from, tt.to)
+		}
+	}
+}
+
+// Test that GeneratePGN writes exact SAN for a game with disambiguation
+func TestGeneratePGNUsesExactSAN(t *testing.T) {
+	g := NewGame()
+	for _, san := range strings.Fields(sanReferenceGames[0].moves) {
+		m, ok := findSAN(g, san)
+		if !ok {
+			t.Fatalf("could not play %s", san)
+		}
+		g.MakeMove(m)
+	}
+
+	pgn := g.GeneratePGN()
+	for _, want := range []string{"11. Bxb5+ Nbd7", "12. O-O-O Rd8", "17. Rd8# 1-0"} {
+		if !strings.Contains(pgn, want) {
+			t.Errorf("PGN missing %q:\n%s", want, pgn)
+		}
+	}
+}
+
+// Test that ParseSAN reads back every move of the reference games
+func TestParseSANReferenceGames(t *testing.T) {
+	for _, game := range sanReferenceGames {
+		g := NewGame()
+		for ply, san := range strings.Fields(game.moves) {
+			want, _ := findSAN(g, san)
+			got, err := g.ParseSAN(san)
+			if err != nil {
+				t.Fatalf("%s ply %d: %s: %v", game.name, ply+1, san, err)
+			}
+			if got != want {
+				t.Fatalf("%s ply %d: %s parsed as %+v, expected %+v", game.name, ply+1, san, got, want)
+			}
+			g.MakeMove(got)
+		}
+	}
+}
+
+// Test tolerated notation variants and precise errors
+func TestParseSAN(t *testing.T) {
+	tests := []struct {
+		fen      string
+		input    string
+		from, to string
+		promo    PieceType
+		err      error
+	}{
+		{StartFEN, "Nf3", "g1", "f3", Empty, nil},
+		{StartFEN, "Nf3!?", "g1", "f3", Empty, nil},
+		{StartFEN, "e4!", "e2", "e4", Empty, nil},
+		{StartFEN, "g1f3", "g1", "f3", Empty, nil},
+		{StartFEN, "Ng1f3", "g1", "f3", Empty, nil},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "exd6", "e5", "d6", Empty, nil},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "exd6e.p.", "e5", "d6", Empty, nil},
+		{"2k5/4P3/8/8/8/8/8/4K3 w - - 0 1", "e8=Q+", "e7", "e8", Queen, nil},
+		{"2k5/4P3/8/8/8/8/8/4K3 w - - 0 1", "e8N", "e7", "e8", Knight, nil},
+		{"6k1/8/8/8/8/8/8/R3K2R w KQ - 0 1", "O-O", "e1", "g1", Empty, nil},
+		{"6k1/8/8/8/8/8/8/R3K2R w KQ - 0 1", "0-0-0", "e1", "c1", Empty, nil},
+		{"6k1/5ppp/8/8/8/8/8/R3K3 w Q - 0 1", "Ra8#", "a1", "a8", Empty, nil},
+		{"4k3/8/8/8/8/8/8/R4RK1 w - - 0 1", "Rd1", "", "", Empty, ErrAmbiguousMove},
+		{StartFEN, "Nf4", "", "", Empty, ErrIllegalMove},
+		{StartFEN, "O-O", "", "", Empty, ErrIllegalMove},
+		{"4k3/8/8/8/1b6/8/3N4/N3K3 w - - 0 1", "Ndb3", "", "", Empty, ErrIllegalMove},
+		{"2k5/4P3/8/8/8/8/8/4K3 w - - 0 1", "e8", "", "", Empty, ErrIllegalMove},
+	}
+
+	for _, tt := range tests {
+		g := NewGame()
+		if err := g.LoadFEN(tt.fen); err != nil {
+			t.Fatalf("%s: %v", tt.fen, err)
+		}
+
+		m, err := g.ParseMoveInput(tt.input)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s: expected %v, got %v", tt.input, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.input, err)
+			continue
+		}
+		if m.From != CoordToIndex(tt.from) || m.To != CoordToIndex(tt.to) || m.Promotion != tt.promo {
+			t.Errorf("%s: got %s%s %v", tt.input, IndexToCoord(m.From), IndexToCoord(m.To), m.Promotion)
+		}
+	}
+}
@@ -2,77 +2,108 @@ package game
 
 // MakeMove executes a move on the board and updates game state
 func (g *Game) MakeMove(m Move) MoveResult {
-	// --- Capture State Snapshot ---
-	var lastMove Move
+	// SAN must be worked out before the move changes the board
+	san := ""
+	if g.current != nil && g.current.child(m) == nil {
+		san = g.MoveToSAN(m)
+	}
+
+	snapshot := g.playMove(m)
 	if len(g.History) > 0 {
-		lastMove = g.History[len(g.History)-1]
+		snapshot.LastMove = g.History[len(g.History)-1]
 	}
+	g.StateHistory = append(g.StateHistory, snapshot)
+	g.History = append(g.History, m)
+
+	// Check for check/checkmate after move; the opponent is now to move.
+	// A variant may end the game in check without it being mate.
+	wasCheck := g.InCheck()
+	wasCheckmate := wasCheck && len(g.GenerateLegalMoves()) == 0 && !g.rules().Outcome(g).IsOver()
 
+	// Create move result
+	result := MoveResult{
+		Move:         m,
+		WasCapture:   snapshot.Captured.Type != Empty || m.MoveType == MoveEnPassant,
+		WasCheck:     wasCheck,
+		WasCheckmate: wasCheckmate,
+		WasCastle:    m.MoveType == MoveCastling,
+		WasPromotion: m.Promotion != Empty,
+		WasIllegal:   false,
+	}
+
+	g.MoveResults = append(g.MoveResults, result)
+	g.positionHashes = append(g.positionHashes, g.Hash())
+	g.recordMove(m, san)
+
+	return result
+}
+
+// playMove applies m to the board, castling rights, en passant square,
+// clocks and turn, and returns what retractMove needs to take it back. It
+// records no history and does not allocate, so search can call it at every
+// node.
+func (g *Game) playMove(m Move) StateSnapshot {
 	snapshot := StateSnapshot{
-		Board:           g.Board,
+		Move:            m,
+		Captured:        g.Board[m.To],
 		Castling:        g.Castling,
 		EnPassantTarget: g.EnPassantTarget,
 		Turn:            g.Turn,
-		LastMove:        lastMove,
+		HalfmoveClock:   g.HalfmoveClock,
+		FullmoveNumber:  g.FullmoveNumber,
+		Hash:            g.hash,
+		Checks:          g.Checks,
+		Promoted:        g.promoted,
 	}
-	g.StateHistory = append(g.StateHistory, snapshot)
-	// ----------------------------------------
-
-	// Store original pieces for sound detection
-	targetPiece := g.Board[m.To]
-
-	wasCapture := targetPiece.Type != Empty
-	wasCastle := m.MoveType == MoveCastling
-	wasPromotion := m.Promotion != Empty
 
-	// Execute the move (existing code remains the same)
-	capturedPiece := g.Board[m.To]
 	movingPiece := g.Board[m.From]
-	g.Board[m.From] = Piece{Type: Empty}
 
-	if m.Promotion != Empty {
-		movingPiece.Type = m.Promotion
+	if m.MoveType == MoveDrop {
+		movingPiece = Piece{Type: m.Piece, Color: g.Turn}
+		g.setSquare(m.To, movingPiece)
+		g.hash ^= zobristPiece(movingPiece, m.To)
+	} else if m.MoveType == MoveCastling {
+		// Lift both pieces before placing them: in Chess960 the king and
+		// rook may land on each other's squares
+		rookFrom, rookTo := g.castlingRookSquares(m)
+		rook := g.Board[rookFrom]
+		snapshot.Captured = Piece{Type: Empty}
+		g.setSquare(m.From, Piece{Type: Empty})
+		g.setSquare(rookFrom, Piece{Type: Empty})
+		g.setSquare(m.To, movingPiece)
+		g.setSquare(rookTo, rook)
+		g.hash ^= zobristPiece(movingPiece, m.From) ^ zobristPiece(movingPiece, m.To) ^
+			zobristPiece(rook, rookFrom) ^ zobristPiece(rook, rookTo)
+	} else {
+		placed := movingPiece
+		if m.Promotion != Empty {
+			placed.Type = m.Promotion
+		}
+		g.setSquare(m.From, Piece{Type: Empty})
+		g.setSquare(m.To, placed)
+		g.hash ^= zobristPiece(movingPiece, m.From) ^ zobristPiece(snapshot.Captured, m.To) ^ zobristPiece(placed, m.To)
 	}
-	g.Board[m.To] = movingPiece
 
-	// --- Special Move Logic ---
 	if m.MoveType == MoveEnPassant {
-		var captureSq int
-		if g.Turn == White {
-			captureSq = m.To - 8
-		} else {
-			captureSq = m.To + 8
-		}
-		g.Board[captureSq] = Piece{Type: Empty}
-		wasCapture = true
+		captureSq := enPassantCaptureSquare(m.To, g.Turn)
+		g.hash ^= zobristPiece(g.Board[captureSq], captureSq)
+		g.setSquare(captureSq, Piece{Type: Empty})
 	}
 
-	if m.MoveType == MoveCastling {
-		switch m.To {
-		case 6: // White Short
-			rook := g.Board[7]
-			g.Board[7] = Piece{Type: Empty}
-			g.Board[5] = rook
-		case 2: // White Long
-			rook := g.Board[0]
-			g.Board[0] = Piece{Type: Empty}
-			g.Board[3] = rook
-		case 62: // Black Short
-			rook := g.Board[63]
-			g.Board[63] = Piece{Type: Empty}
-			g.Board[61] = rook
-		case 58: // Black Long
-			rook := g.Board[56]
-			g.Board[56] = Piece{Type: Empty}
-			g.Board[59] = rook
-		}
+	if (snapshot.Captured.Type != Empty || m.MoveType == MoveEnPassant) && g.Variant != nil && g.Variant.Explodes() {
+		snapshot.Exploded = g.explode(m.To)
+	}
+
+	if g.Variant != nil && g.Variant.HasPockets() {
+		g.pocketMove(m, snapshot.Captured)
 	}
 
 	// Update game state
+	// A double step from the first rank in Horde cannot be taken en passant
 	g.EnPassantTarget = -1
 	if movingPiece.Type == Pawn {
 		diff := m.To - m.From
-		if diff == 16 || diff == -16 {
+		if (diff == 16 && m.From/8 == 1) || (diff == -16 && m.From/8 == 6) {
 			g.EnPassantTarget = m.From + (diff / 2)
 		}
 	}
@@ -87,108 +118,146 @@ func (g *Game) MakeMove(m Move) MoveResult {
 			g.Castling.BlackQueenSide = false
 		}
 	}
-
-	if movingPiece.Type == Rook {
-		// White Rooks
-		if m.From == 7 { // h1
-			g.Castling.WhiteKingSide = false
-		}
-		if m.From == 0 { // a1
-			g.Castling.WhiteQueenSide = false
-		}
-		// Black Rooks
-		if m.From == 63 { // h8
-			g.Castling.BlackKingSide = false
-		}
-		if m.From == 56 { // a8
-			g.Castling.BlackQueenSide = false
+	// A castling rook leaving or captured on its square loses that side's right
+	for c, rooks := range g.castlingRooks {
+		for i, rook := range rooks {
+			if m.From == rook || m.To == rook {
+				*g.Castling.right(Color(c), i == 0) = false
+			}
 		}
 	}
 
-	if capturedPiece.Type == Rook {
-		switch m.To {
-		case 7: // h1
-			g.Castling.WhiteKingSide = false
-		case 0: // a1
-			g.Castling.WhiteQueenSide = false
-		case 63: // h8
-			g.Castling.BlackKingSide = false
-		case 56: // a8
-			g.Castling.BlackQueenSide = false
-		}
+	// Update move clocks; pawn drops count as pawn moves
+	if movingPiece.Type == Pawn || snapshot.Captured.Type != Empty {
+		g.HalfmoveClock = 0
+	} else {
+		g.HalfmoveClock++
+	}
+	if g.Turn == Black {
+		g.FullmoveNumber++
 	}
 
-	// Record history
-	g.History = append(g.History, m)
+	g.Turn = g.Turn.Opponent()
+	g.hash ^= zobristCastlingRights(snapshot.Castling) ^ zobristCastlingRights(g.Castling) ^ zobristBlack
 
-	// Check for check/checkmate after move
-	opponentColor := Black
-	if g.Turn == Black {
-		opponentColor = White
+	if g.Variant != nil && g.Variant.CountsChecks() && g.InCheck() {
+		g.Checks[snapshot.Turn]++
+	}
+	return snapshot
+}
+
+// retractMove takes back the move recorded in snapshot, which must be the
+// last move played
+func (g *Game) retractMove(snapshot StateSnapshot) {
+	m := snapshot.Move
+	g.Turn = snapshot.Turn
+	g.Castling = snapshot.Castling
+	g.EnPassantTarget = snapshot.EnPassantTarget
+	g.HalfmoveClock = snapshot.HalfmoveClock
+	g.FullmoveNumber = snapshot.FullmoveNumber
+	g.hash = snapshot.Hash
+	g.Checks = snapshot.Checks
+	if g.Variant != nil && g.Variant.HasPockets() {
+		g.unpocketMove(snapshot)
+	}
+	g.promoted = snapshot.Promoted
+	if snapshot.Exploded != 0 {
+		g.unexplode(m.To, snapshot.Exploded)
 	}
 
-	wasCheck := g.Board.InCheck(opponentColor)
-	wasCheckmate := false
+	movingPiece := g.Board[m.To]
 
-	if wasCheck {
-		// Generate opponent's moves to check if it's checkmate
-		tempGame := &Game{
-			Board:           g.Board,
-			Turn:            opponentColor,
-			Castling:        g.Castling,
-			EnPassantTarget: g.EnPassantTarget,
-		}
-		opponentMoves := tempGame.GenerateLegalMoves()
-		wasCheckmate = len(opponentMoves) == 0
+	if m.MoveType == MoveDrop {
+		g.setSquare(m.To, Piece{Type: Empty})
+		return
 	}
 
-	// Create move result
-	result := MoveResult{
-		Move:         m,
-		WasCapture:   wasCapture,
-		WasCheck:     wasCheck,
-		WasCheckmate: wasCheckmate,
-		WasCastle:    wasCastle,
-		WasPromotion: wasPromotion,
-		WasIllegal:   false,
+	if m.MoveType == MoveCastling {
+		rookFrom, rookTo := g.castlingRookSquares(m)
+		rook := g.Board[rookTo]
+		g.setSquare(m.To, Piece{Type: Empty})
+		g.setSquare(rookTo, Piece{Type: Empty})
+		g.setSquare(m.From, movingPiece)
+		g.setSquare(rookFrom, rook)
+		return
 	}
 
-	g.MoveResults = append(g.MoveResults, result)
+	if m.Promotion != Empty {
+		movingPiece.Type = Pawn
+	}
+	g.setSquare(m.From, movingPiece)
+	g.setSquare(m.To, snapshot.Captured)
 
-	// Switch turn
-	if g.Turn == White {
-		g.Turn = Black
-	} else {
-		g.Turn = White
+	if m.MoveType == MoveEnPassant {
+		g.setSquare(enPassantCaptureSquare(m.To, g.Turn), Piece{Type: Pawn, Color: g.Turn.Opponent()})
 	}
+}
 
-	return result
+// enPassantCaptureSquare returns the square of the pawn taken by an en
+// passant capture of color c landing on to
+func enPassantCaptureSquare(to int, c Color) int {
+	if c == White {
+		return to - 8
+	}
+	return to + 8
 }
 
-// UndoMove reverts the last move using the StateStack
+// UndoMove takes back the last move and removes it from the move tree. Use
+// Back to step back while keeping the move as a line to return to.
 func (g *Game) UndoMove() {
+	n := g.current
+	if n == nil || n.Parent == nil {
+		g.unmakeMove()
+		return
+	}
+
+	g.Back()
+	i := n.Parent.index(n)
+	n.Parent.Children = append(n.Parent.Children[:i], n.Parent.Children[i+1:]...)
+}
+
+// CapturedPieces returns the pieces taken off the board along the line to
+// the current position, in the order they went: captured pieces, pawns taken
+// en passant and, in Atomic, every piece caught in an explosion
+func (g *Game) CapturedPieces() []Piece {
+	var pieces []Piece
+	for _, s := range g.StateHistory {
+		if s.Captured.Type != Empty {
+			pieces = append(pieces, s.Captured)
+		}
+		if s.Move.MoveType == MoveEnPassant {
+			pieces = append(pieces, Piece{Type: Pawn, Color: s.Turn.Opponent()})
+		}
+		pieces = append(pieces, explodedPieces(s.Exploded)...)
+	}
+	return pieces
+}
+
+// unmakeMove reverts the last move using the StateStack
+func (g *Game) unmakeMove() {
 	// 1. Check if StateHistory is empty
 	if len(g.StateHistory) == 0 {
 		return
 	}
 
-	// 2. Pop the last snapshot
+	// 2. Pop the last snapshot and take its move back
 	lastIndex := len(g.StateHistory) - 1
-	snapshot := g.StateHistory[lastIndex]
-
-	// 3. Overwrite game state with values from the snapshot
-	g.Board = snapshot.Board
-	g.Castling = snapshot.Castling
-	g.EnPassantTarget = snapshot.EnPassantTarget
-	g.Turn = snapshot.Turn
-
-	// 4. Remove the snapshot from the list
+	g.retractMove(g.StateHistory[lastIndex])
 	g.StateHistory = g.StateHistory[:lastIndex]
 
-	// 5. Sync the Move History
+	// Taking back a move also takes back any resignation or agreed result
+	g.declared = Outcome{}
+
+	// 3. Sync the Move History
 	if len(g.History) > 0 {
 		g.History = g.History[:len(g.History)-1]
 	}
+	if len(g.MoveResults) > 0 {
+		g.MoveResults = g.MoveResults[:len(g.MoveResults)-1]
+	}
+	if len(g.positionHashes) > 1 {
+		g.positionHashes = g.positionHashes[:len(g.positionHashes)-1]
+	}
 }
 
 // GetLastMoveResult returns the last move result
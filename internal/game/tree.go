@@ -0,0 +1,206 @@
+package game
+
+import "fmt"
+
+// MoveNode is a position in the move tree, reached by playing Move from its
+// Parent. Children[0] continues the main line; any further children are
+// variations. The root node has no move and holds the game comment.
+type MoveNode struct {
+	Move       Move
+	SAN        string
+	Annotation Annotation
+	Parent     *MoveNode
+	Children   []*MoveNode
+
+	declared Outcome // Result declared in this position, such as a resignation
+}
+
+// child returns the child reached by m, if it has been played before
+func (n *MoveNode) child(m Move) *MoveNode {
+	for _, c := range n.Children {
+		if sameMove(c.Move, m) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Ply returns the number of moves from the root to the node
+func (n *MoveNode) Ply() int {
+	ply := 0
+	for ; n.Parent != nil; n = n.Parent {
+		ply++
+	}
+	return ply
+}
+
+// Path returns the child index taken at each step from the root, so that
+// NodeAt(n.Path()) == n
+func (n *MoveNode) Path() []int {
+	path := make([]int, n.Ply())
+	for i := len(path) - 1; i >= 0; i-- {
+		path[i] = n.Parent.index(n)
+		n = n.Parent
+	}
+	return path
+}
+
+// IsMainLine reports whether the node lies on the main line
+func (n *MoveNode) IsMainLine() bool {
+	for ; n.Parent != nil; n = n.Parent {
+		if n.Parent.Children[0] != n {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *MoveNode) index(c *MoveNode) int {
+	for i, child := range n.Children {
+		if child == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// resetTree starts a new move tree at the current position
+func (g *Game) resetTree() {
+	g.Root = &MoveNode{}
+	g.current = g.Root
+}
+
+// recordMove steps the move tree along m, adding a variation if m has not
+// been played from the current node before. san is m in the position before
+// it was played.
+func (g *Game) recordMove(m Move, san string) {
+	if g.current == nil {
+		return
+	}
+	if c := g.current.child(m); c != nil {
+		g.current = c
+		g.declared = c.declared
+		return
+	}
+	c := &MoveNode{Move: m, SAN: san, Parent: g.current}
+	g.current.Children = append(g.current.Children, c)
+	g.current = c
+}
+
+// CurrentNode returns the node of the current position, or nil for copies
+// made with Clone, which do not keep a move tree
+func (g *Game) CurrentNode() *MoveNode {
+	return g.current
+}
+
+// NodeAt returns the node reached by following path from the root, or nil
+// if there is no such node
+func (g *Game) NodeAt(path []int) *MoveNode {
+	n := g.Root
+	for _, i := range path {
+		if n == nil || i < 0 || i >= len(n.Children) {
+			return nil
+		}
+		n = n.Children[i]
+	}
+	return n
+}
+
+// MainLine returns the main line nodes after the root, in order
+func (g *Game) MainLine() []*MoveNode {
+	var line []*MoveNode
+	for n := g.Root; n != nil && len(n.Children) > 0; n = n.Children[0] {
+		line = append(line, n.Children[0])
+	}
+	return line
+}
+
+// Back steps to the previous position while keeping the move in the tree.
+// It returns false at the start of the game.
+func (g *Game) Back() bool {
+	if g.current == nil || g.current.Parent == nil {
+		return false
+	}
+	g.unmakeMove()
+	g.current = g.current.Parent
+	g.declared = g.current.declared
+	return true
+}
+
+// Forward plays the next main line move from the current position. It
+// returns false at the end of the line.
+func (g *Game) Forward() bool {
+	if g.current == nil || len(g.current.Children) == 0 {
+		return false
+	}
+	g.MakeMove(g.current.Children[0].Move)
+	return true
+}
+
+// GoTo moves to any node of the tree, taking back moves to the common
+// ancestor and replaying the line down to n
+func (g *Game) GoTo(n *MoveNode) error {
+	if !g.inTree(n) {
+		return fmt.Errorf("node is not part of this game")
+	}
+
+	ancestors := make(map[*MoveNode]bool)
+	for a := n; a != nil; a = a.Parent {
+		ancestors[a] = true
+	}
+	for !ancestors[g.current] {
+		g.Back()
+	}
+
+	var line []*MoveNode
+	for a := n; a != g.current; a = a.Parent {
+		line = append(line, a)
+	}
+	for i := len(line) - 1; i >= 0; i-- {
+		g.MakeMove(line[i].Move)
+	}
+	return nil
+}
+
+// PromoteVariation makes n the main continuation of its parent, demoting
+// the previous main move to the first variation
+func (g *Game) PromoteVariation(n *MoveNode) error {
+	if !g.inTree(n) || n.Parent == nil {
+		return fmt.Errorf("node is not a move in this game")
+	}
+
+	siblings := n.Parent.Children
+	i := n.Parent.index(n)
+	copy(siblings[1:i+1], siblings[:i])
+	siblings[0] = n
+	return nil
+}
+
+// inTree reports whether n belongs to the game's move tree
+func (g *Game) inTree(n *MoveNode) bool {
+	if n == nil || g.Root == nil {
+		return false
+	}
+	for n.Parent != nil {
+		n = n.Parent
+	}
+	return n == g.Root
+}
+
+// moveTree returns the game's move tree, rebuilding the current line for
+// copies that do not keep one
+func (g *Game) moveTree() *MoveNode {
+	if g.Root != nil {
+		return g.Root
+	}
+
+	root := &MoveNode{}
+	replay, node := g.rootPosition(), root
+	for _, m := range g.History {
+		c := &MoveNode{Move: m, SAN: replay.MoveToSAN(m), Parent: node}
+		node.Children = []*MoveNode{c}
+		replay.MakeMove(m)
+		node = c
+	}
+	return root
+}
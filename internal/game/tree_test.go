@@ -0,0 +1,143 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+// playSAN plays a sequence of SAN moves, failing the test on the first error
+func playSAN(t *testing.T, g *Game, moves ...string) {
+	t.Helper()
+	for _, san := range moves {
+		m, err := g.ParseSAN(san)
+		if err != nil {
+			t.Fatalf("ParseSAN(%q): %v", san, err)
+		}
+		g.MakeMove(m)
+	}
+}
+
+// Test that stepping back and playing another move keeps the old line
+func TestMoveTreeVariations(t *testing.T) {
+	g := NewGame()
+	playSAN(t, g, "e4", "e5", "Nf3")
+
+	if !g.Back() || !g.Back() {
+		t.Fatal("Expected to step back twice")
+	}
+	playSAN(t, g, "c5")
+
+	if len(g.History) != 2 || g.CurrentNode().SAN != "c5" {
+		t.Fatalf("Expected to be after 1... c5, got %d moves", len(g.History))
+	}
+	after := g.NodeAt([]int{0})
+	if len(after.Children) != 2 || after.Children[0].SAN != "e5" || after.Children[1].SAN != "c5" {
+		t.Fatalf("Expected e5 as main line and c5 as variation")
+	}
+	if g.CurrentNode().IsMainLine() {
+		t.Error("1... c5 should be a variation")
+	}
+
+	// Jump back into the main line and follow it to the end
+	if err := g.GoTo(after.Children[0]); err != nil {
+		t.Fatalf("GoTo: %v", err)
+	}
+	if !g.Forward() || g.CurrentNode().SAN != "Nf3" || g.Forward() {
+		t.Error("Expected Forward to replay 2. Nf3 and stop there")
+	}
+	if want := "rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2"; g.FEN() != want {
+		t.Errorf("Expected %s, got %s", want, g.FEN())
+	}
+
+	// Replaying an existing move follows it instead of adding a duplicate
+	g.GoTo(g.Root)
+	playSAN(t, g, "e4")
+	if len(g.Root.Children) != 1 || len(g.History) != 1 {
+		t.Errorf("Expected e4 to be reused, got %d root children", len(g.Root.Children))
+	}
+
+	if err := g.PromoteVariation(after.Children[1]); err != nil {
+		t.Fatalf("PromoteVariation: %v", err)
+	}
+	if after.Children[0].SAN != "c5" || after.Children[1].SAN != "e5" {
+		t.Error("Expected c5 to become the main line")
+	}
+	if path := after.Children[1].Children[0].Path(); len(path) != 3 || path[1] != 1 {
+		t.Errorf("Expected path [0 1 0] to 2. Nf3, got %v", path)
+	}
+}
+
+// Test that UndoMove takes a move back out of the tree
+func TestUndoMoveRemovesNode(t *testing.T) {
+	g := NewGame()
+	playSAN(t, g, "d4", "d5")
+	g.UndoMove()
+
+	if len(g.NodeAt([]int{0}).Children) != 0 {
+		t.Error("UndoMove should remove the move from the tree")
+	}
+	if err := g.GoTo(NewGame().Root); err == nil {
+		t.Error("Expected an error for a node from another game")
+	}
+}
+
+// Test that variations are exported and imported as PGN ( ... )
+func TestPGNVariations(t *testing.T) {
+	g := NewGame()
+	playSAN(t, g, "e4", "e5", "Nf3", "Nc6")
+	g.Back()
+	g.Back()
+	playSAN(t, g, "Bc4", "Nf6")
+	g.Back()
+	playSAN(t, g, "Bc5")
+	g.GoTo(g.MainLine()[3])
+
+	want := "1. e4 e5 2. Nf3 (2. Bc4 Nf6 (2... Bc5)) 2... Nc6 *"
+	pgn := g.GeneratePGN()
+	if !strings.Contains(pgn, "\n"+want+"\n") {
+		t.Errorf("Expected movetext %q in:\n%s", want, pgn)
+	}
+
+	imported, err := ParsePGN(pgn)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if imported.FEN() != g.FEN() {
+		t.Errorf("Expected import to end on the main line, got %s", imported.FEN())
+	}
+	if imported.GeneratePGN() != pgn {
+		t.Errorf("Round trip changed the PGN:\n%s", imported.GeneratePGN())
+	}
+
+	if _, err := ParsePGN("(1. d4) 1. e4 *"); err == nil {
+		t.Error("Expected error for a variation before the first move")
+	}
+}
+
+// Test that the exported result is the one at the end of the main line
+func TestPGNResultAfterBack(t *testing.T) {
+	g := NewGame()
+	playSAN(t, g, "f3", "e5", "g4", "Qh4#")
+	g.Back()
+	g.Back()
+	if pgn := g.GeneratePGN(); !strings.Contains(pgn, "[Result \"0-1\"]") || !strings.HasSuffix(pgn, "Qh4# 0-1\n") {
+		t.Errorf("Expected the checkmate result after stepping back, got:\n%s", pgn)
+	}
+
+	g = NewGame()
+	playSAN(t, g, "e4", "e5")
+	if err := g.Resign(Black); err != nil {
+		t.Fatalf("Resign: %v", err)
+	}
+	g.Back()
+	if g.Outcome().IsOver() {
+		t.Error("Stepping back should leave the resigned position")
+	}
+	if pgn := g.GeneratePGN(); !strings.HasSuffix(pgn, "e5 1-0\n") {
+		t.Errorf("Expected the resignation to be exported, got:\n%s", pgn)
+	}
+	g.Forward()
+	if o := g.Outcome(); o.Termination != TerminationResignation {
+		t.Errorf("Expected the resignation back after Forward, got %v", o)
+	}
+}
@@ -8,6 +8,14 @@ const (
 	Black
 )
 
+// Opponent returns the other color
+func (c Color) Opponent() Color {
+	if c == White {
+		return Black
+	}
+	return White
+}
+
 func (c Color) String() string {
 	if c == White {
 		return "White"
@@ -63,15 +71,17 @@ const (
 	MoveNormal MoveType = iota
 	MoveCastling
 	MoveEnPassant
+	MoveDrop // A piece put on the board from the pocket, in Crazyhouse
 )
 
-// Move represents a single move
+// Move represents a single move. A drop has From equal to To and Piece
+// set to the piece taken from the pocket.
 type Move struct {
 	From      int
 	To        int
 	Piece     PieceType
 	Promotion PieceType // If pawn promotion, what type?
-	MoveType  MoveType  // Normal, Castling, or EnPassant
+	MoveType  MoveType  // Normal, Castling, EnPassant or Drop
 }
 
 func (p Piece) String() string {
@@ -127,23 +137,47 @@ type CastlingRights struct {
 	BlackQueenSide bool
 }
 
-// StateSnapshot captures the game state before a move is made
+// StateSnapshot records what a move changed, so that it can be taken back
+// without keeping a copy of the board
 type StateSnapshot struct {
-	Board           Board
+	Move            Move
+	Captured        Piece  // Piece that stood on Move.To; Empty for en passant
+	Hash            uint64 // Zobrist hash before the move, without the en passant square
+	Checks          [2]int
+	Promoted        Bitboard // Promoted pieces before the move; the pockets are restored from the move itself
+	Exploded        uint64   // Pieces blown up by an Atomic capture, packed by explode
 	Castling        CastlingRights
 	EnPassantTarget int
 	Turn            Color
 	LastMove        Move
+	HalfmoveClock   int
+	FullmoveNumber  int
 }
 
 type Game struct {
-	Board           Board
+	Board           Board // Set up positions with SetBoard; see there
 	Turn            Color
 	History         []Move
 	StateHistory    []StateSnapshot // Stack for Undo functionality
 	Castling        CastlingRights
 	EnPassantTarget int
-	MoveResults     []MoveResult // Track move results for sound
+	HalfmoveClock   int               // Plies since the last capture or pawn move
+	FullmoveNumber  int               // Starts at 1, incremented after Black moves
+	MoveResults     []MoveResult      // Track move results for sound
+	Tags            map[string]string // PGN tag pairs such as Event, White and Black
+	Root            *MoveNode         // Move tree with the main line and variations; History is the line to the current node
+	Chess960        bool              // Fischer Random rules: castling rights may name rook files, UCI castles king-takes-rook
+	Variant         Variant           // Rules beyond standard chess; nil plays standard chess
+	Checks          [2]int            // Checks given by each color, kept when the variant counts them
+	Pockets         [2][7]int         // Captured pieces in hand, by Color and PieceType, in variants with drops
+
+	bb             bitboards // Board as piece sets, kept up to date by every move
+	castlingRooks  [2][2]int // Starting squares of the castling rooks, by Color and side (king side first)
+	promoted       Bitboard  // Pieces that were promoted, which go to the pocket as pawns when captured
+	hash           uint64    // Zobrist hash without the en passant square, kept up to date by every move
+	positionHashes []uint64  // Hash of every position reached, for repetition detection
+	current        *MoveNode // Node of the current position in the move tree
+	declared       Outcome   // Result set by resignation, timeout, agreement or a draw claim
 }
 
 // NewGame returns a game with the starting position
@@ -154,6 +188,8 @@ func NewGame() *Game {
 		StateHistory:    make([]StateSnapshot, 0),
 		EnPassantTarget: -1,
 		Castling:        CastlingRights{true, true, true, true},
+		FullmoveNumber:  1,
+		castlingRooks:   standardCastlingRooks,
 	}
 	g.LoadFEN(StartFEN)
 	return g
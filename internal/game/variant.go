@@ -0,0 +1,196 @@
+package game
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Variant is a set of rules layered on top of standard chess. A game plays
+// standard chess when Game.Variant is nil. Variants embed StandardRules and
+// override only the parts they change.
+type Variant interface {
+	// Name is the variant's name as written in the PGN Variant tag
+	Name() string
+	// StartFEN is the position new games of the variant start from
+	StartFEN() string
+	// FilterMoves adjusts the legal moves the standard rules generate for
+	// the side to move, e.g. to end the game by returning none
+	FilterMoves(g *Game, moves []Move) []Move
+	// Outcome ends the game under the variant's own rules. It returns the
+	// zero Outcome to let the standard rules decide.
+	Outcome(g *Game) Outcome
+	// CountsChecks reports whether the game keeps Game.Checks up to date
+	// and writes it as a seventh FEN field, e.g. "+1+0"
+	CountsChecks() bool
+	// HasPockets reports whether captured pieces go to the capturer's
+	// pocket, from where they can be dropped, and FENs carry the pockets
+	// in brackets after the placement, e.g. "[Nnp]"
+	HasPockets() bool
+	// Explodes reports whether a capture blows up the capturing piece and
+	// every piece but pawns next to the captured one, as in Atomic
+	Explodes() bool
+	// InsufficientMaterial reports whether neither side can win any more,
+	// which draws the game
+	InsufficientMaterial(g *Game) bool
+	// CanWin reports whether color c could still win by some series of
+	// legal moves, which decides whether its opponent's timeout loses
+	CanWin(g *Game, c Color) bool
+	// RoyalKing reports whether the king must be kept out of check. Where
+	// it is not, kings can be captured, pawns may promote to king and
+	// nobody castles.
+	RoyalKing() bool
+}
+
+// StandardRules is standard chess as a Variant
+type StandardRules struct{}
+
+func (StandardRules) Name() string                             { return "Standard" }
+func (StandardRules) StartFEN() string                         { return StartFEN }
+func (StandardRules) FilterMoves(g *Game, moves []Move) []Move { return moves }
+func (StandardRules) Outcome(g *Game) Outcome                  { return Outcome{} }
+func (StandardRules) CountsChecks() bool                       { return false }
+func (StandardRules) HasPockets() bool                         { return false }
+func (StandardRules) Explodes() bool                           { return false }
+func (StandardRules) RoyalKing() bool                          { return true }
+
+func (StandardRules) InsufficientMaterial(g *Game) bool {
+	return g.Board.IsInsufficientMaterial()
+}
+
+func (StandardRules) CanWin(g *Game, c Color) bool {
+	return g.Board.hasMatingMaterial(c)
+}
+
+// KingOfTheHill is won by checkmate or by bringing the king to one of the
+// four center squares
+type KingOfTheHill struct{ StandardRules }
+
+// hill holds d4, e4, d5 and e5
+const hill = Bitboard(1)<<27 | 1<<28 | 1<<35 | 1<<36
+
+func (KingOfTheHill) Name() string { return "King of the Hill" }
+
+// Material never runs short, since the king can walk to the hill
+func (KingOfTheHill) InsufficientMaterial(g *Game) bool { return false }
+func (KingOfTheHill) CanWin(g *Game, c Color) bool      { return true }
+
+func (v KingOfTheHill) FilterMoves(g *Game, moves []Move) []Move {
+	if v.Outcome(g).IsOver() {
+		return nil
+	}
+	return moves
+}
+
+func (KingOfTheHill) Outcome(g *Game) Outcome {
+	for _, c := range []Color{White, Black} {
+		if king := g.Board.kingSquare(c); king >= 0 && hill.Has(king) {
+			return Outcome{Result: winFor(c), Termination: TerminationKingOfTheHill}
+		}
+	}
+	return Outcome{}
+}
+
+// ThreeCheck is won by checkmate or by giving check three times
+type ThreeCheck struct{ StandardRules }
+
+// ThreeCheckLimit is the number of checks that wins a Three-check game
+const ThreeCheckLimit = 3
+
+func (ThreeCheck) Name() string       { return "Three-check" }
+func (ThreeCheck) StartFEN() string   { return StartFEN + " +0+0" }
+func (ThreeCheck) CountsChecks() bool { return true }
+
+// InsufficientMaterial is only true with two bare kings, since any other
+// piece can still give the checks that win
+func (v ThreeCheck) InsufficientMaterial(g *Game) bool {
+	return !v.CanWin(g, White) && !v.CanWin(g, Black)
+}
+
+// CanWin reports whether c has a piece besides the king to give check with
+func (ThreeCheck) CanWin(g *Game, c Color) bool { return hasPieceBesidesKing(&g.Board, c) }
+
+func (v ThreeCheck) FilterMoves(g *Game, moves []Move) []Move {
+	if v.Outcome(g).IsOver() {
+		return nil
+	}
+	return moves
+}
+
+func (ThreeCheck) Outcome(g *Game) Outcome {
+	for _, c := range []Color{White, Black} {
+		if g.Checks[c] >= ThreeCheckLimit {
+			return Outcome{Result: winFor(c), Termination: TerminationThreeCheck}
+		}
+	}
+	return Outcome{}
+}
+
+// hasPieceBesidesKing reports whether color c has anything but its king
+func hasPieceBesidesKing(b *Board, c Color) bool {
+	for _, p := range b {
+		if p.Type != Empty && p.Type != King && p.Color == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Variants lists the rule sets games can be played under
+var Variants = []Variant{StandardRules{}, KingOfTheHill{}, ThreeCheck{}, Crazyhouse{}, Atomic{}, Antichess{}, Horde{}, RacingKings{}}
+
+// VariantByName finds a variant by its name, ignoring case, spaces and
+// punctuation, so "King of the Hill" and "kingofthehill" both match
+func VariantByName(name string) (Variant, bool) {
+	key := variantKey(name)
+	for _, v := range Variants {
+		if variantKey(v.Name()) == key {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func variantKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// NewVariantGame returns a game of the variant from its start position
+func NewVariantGame(v Variant) *Game {
+	g := NewGame()
+	if _, standard := v.(StandardRules); !standard {
+		g.Variant = v
+	}
+	g.LoadFEN(v.StartFEN())
+	return g
+}
+
+// InCheck reports whether the side to move is in check under the game's
+// rules
+func (g *Game) InCheck() bool {
+	return checked(&g.bb, g.Turn, g.rules())
+}
+
+// checked reports whether the king of color c is in check under the rules
+// of v
+func checked(bb *bitboards, c Color, v Variant) bool {
+	switch {
+	case !v.RoyalKing():
+		return false
+	case v.Explodes():
+		return bb.atomicInCheck(c)
+	}
+	return bb.inCheck(c)
+}
+
+// rules returns the game's variant, with nil standing for standard chess
+func (g *Game) rules() Variant {
+	if g.Variant == nil {
+		return StandardRules{}
+	}
+	return g.Variant
+}
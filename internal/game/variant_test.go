@@ -0,0 +1,124 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVariantByName(t *testing.T) {
+	for name, want := range map[string]string{
+		"King of the Hill": "King of the Hill",
+		"kingofthehill":    "King of the Hill",
+		"three-check":      "Three-check",
+		"ThreeCheck":       "Three-check",
+		"standard":         "Standard",
+	} {
+		v, ok := VariantByName(name)
+		if !ok || v.Name() != want {
+			t.Errorf("VariantByName(%q) = %v, %v; want %s", name, v, ok, want)
+		}
+	}
+	if _, ok := VariantByName("bughouse"); ok {
+		t.Error("Unknown variants should not be found")
+	}
+}
+
+func TestKingOfTheHill(t *testing.T) {
+	g := NewVariantGame(KingOfTheHill{})
+	if err := g.LoadFEN("4k3/8/8/8/8/3K4/8/8 w - - 0 1"); err != nil {
+		t.Fatal(err)
+	}
+	if o := g.Outcome(); o.IsOver() {
+		t.Fatalf("A bare king can still reach the hill, got %v", o)
+	}
+
+	best, err := g.GetBestMove(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hill.Has(best.To) {
+		t.Errorf("AI should walk onto the hill, played %s", g.MoveToSAN(best))
+	}
+
+	g.MakeMove(Move{From: 19, To: 27, Piece: King}) // Kd4
+	if o := g.Outcome(); o.Result != WhiteWins || o.Termination != TerminationKingOfTheHill {
+		t.Errorf("Kd4 should win by king of the hill, got %v", o)
+	}
+	if len(g.GenerateLegalMoves()) != 0 {
+		t.Error("No moves should remain once the game is won")
+	}
+
+	// Standard chess ignores the hill
+	g = NewGame()
+	g.LoadFEN("4k3/8/8/8/3K4/8/4P3/8 b - - 0 1")
+	if g.Outcome().IsOver() {
+		t.Error("A king in the center should not end a standard game")
+	}
+}
+
+func TestThreeCheck(t *testing.T) {
+	g := NewVariantGame(ThreeCheck{})
+	if g.FEN() != StartFEN+" +0+0" {
+		t.Errorf("Three-check FEN should count checks, got %q", g.FEN())
+	}
+	if err := g.LoadFEN("4k3/8/8/8/8/8/8/R3K3 w - - 0 1 +2+0"); err != nil {
+		t.Fatal(err)
+	}
+
+	g.MakeMove(Move{From: 0, To: 56, Piece: Rook}) // Ra8+, the third check
+	if g.Checks != [2]int{3, 0} || !strings.HasSuffix(g.FEN(), " +3+0") {
+		t.Errorf("Checks = %v, FEN %s; want 3 checks by White", g.Checks, g.FEN())
+	}
+	if o := g.Outcome(); o.Result != WhiteWins || o.Termination != TerminationThreeCheck {
+		t.Errorf("Third check should win, got %v", o)
+	}
+	if r := g.GetLastMoveResult(); !r.WasCheck || r.WasCheckmate {
+		t.Errorf("Third check is not checkmate: %+v", r)
+	}
+
+	g.UndoMove()
+	if g.Checks != [2]int{2, 0} || g.Outcome().IsOver() {
+		t.Errorf("Undo should restore the check count, got %v", g.Checks)
+	}
+
+	// A lone knight can still give the third check, two bare kings cannot
+	if err := g.LoadFEN("4k3/8/8/8/8/8/8/1N2K3 w - - 0 1 +2+0"); err != nil {
+		t.Fatal(err)
+	}
+	if o := g.Outcome(); o.IsOver() {
+		t.Errorf("K+N vs K with two checks given should go on, got %v", o)
+	}
+	if err := g.LoadFEN("4k3/8/8/8/8/8/8/4K3 w - - 0 1 +2+0"); err != nil {
+		t.Fatal(err)
+	}
+	if o := g.Outcome(); o.Termination != TerminationInsufficientMaterial {
+		t.Errorf("Two bare kings should draw, got %v", o)
+	}
+
+	if err := NewGame().LoadFEN("4k3/8/8/8/8/8/8/R3K3 w - - 0 1 +2+0"); err == nil {
+		t.Error("Standard chess FEN should not take a checks field")
+	}
+}
+
+func TestVariantPGNRoundTrip(t *testing.T) {
+	g := NewVariantGame(ThreeCheck{})
+	for _, san := range []string{"e4", "e5", "Bc4", "Nc6", "Bxf7+"} {
+		m, err := g.ParseSAN(san)
+		if err != nil {
+			t.Fatalf("%s: %v", san, err)
+		}
+		g.MakeMove(m)
+	}
+
+	pgn := g.GeneratePGN()
+	if !strings.Contains(pgn, `[Variant "Three-check"]`) || strings.Contains(pgn, "[FEN") {
+		t.Errorf("PGN should name the variant and have no FEN tag:\n%s", pgn)
+	}
+	parsed, err := ParsePGN(pgn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := parsed.Variant.(ThreeCheck); !ok || parsed.FEN() != g.FEN() {
+		t.Errorf("Round trip gave %s under %v, want %s", parsed.FEN(), parsed.Variant, g.FEN())
+	}
+}
@@ -0,0 +1,122 @@
+package game
+
+// Zobrist keys: one random number per piece on each square, per castling
+// right, per en passant file, for Black to move and, in variants, per count
+// of checks given and of each piece in hand. A position's hash is the XOR of
+// the keys of everything in it, so a move updates it by XORing out what
+// changed and XORing in the new state.
+var (
+	zobristPieces    [2][7][64]uint64 // Indexed by Color, PieceType and square
+	zobristCastling  [4]uint64        // White short, White long, Black short, Black long
+	zobristEnPassant [8]uint64        // Indexed by the file of the en passant square
+	zobristBlack     uint64
+	zobristChecks    [2][ThreeCheckLimit + 1]uint64   // Indexed by Color and checks given; none for 0
+	zobristPockets   [2][7][maxPocketCount + 1]uint64 // Indexed by Color, PieceType and count in hand; none for 0
+)
+
+func init() {
+	// A fixed seed keeps hashes stable between runs, so they can be stored
+	// with saved games or in an opening book
+	state := uint64(0x9E3779B97F4A7C15)
+	next := func() uint64 {
+		// splitmix64
+		state += 0x9E3779B97F4A7C15
+		z := state
+		z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+		z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+		return z ^ (z >> 31)
+	}
+
+	for c := range zobristPieces {
+		for pt := Pawn; pt <= King; pt++ {
+			for sq := range zobristPieces[c][pt] {
+				zobristPieces[c][pt][sq] = next()
+			}
+		}
+	}
+	for i := range zobristCastling {
+		zobristCastling[i] = next()
+	}
+	for i := range zobristEnPassant {
+		zobristEnPassant[i] = next()
+	}
+	zobristBlack = next()
+	for c := range zobristChecks {
+		for n := 1; n <= ThreeCheckLimit; n++ {
+			zobristChecks[c][n] = next()
+		}
+	}
+	for c := range zobristPockets {
+		for pt := Pawn; pt <= Queen; pt++ {
+			for n := 1; n <= maxPocketCount; n++ {
+				zobristPockets[c][pt][n] = next()
+			}
+		}
+	}
+}
+
+// zobristPiece returns the key of p standing on sq, or 0 for an empty square
+func zobristPiece(p Piece, sq int) uint64 {
+	if p.Type == Empty {
+		return 0
+	}
+	return zobristPieces[p.Color][p.Type][sq]
+}
+
+// zobristCastlingRights returns the combined key of the rights that are kept
+func zobristCastlingRights(c CastlingRights) uint64 {
+	var key uint64
+	for i, ok := range [4]bool{c.WhiteKingSide, c.WhiteQueenSide, c.BlackKingSide, c.BlackQueenSide} {
+		if ok {
+			key ^= zobristCastling[i]
+		}
+	}
+	return key
+}
+
+// Hash returns the Zobrist hash of the position: piece placement, side to
+// move, castling rights and the en passant square, plus any checks given and
+// pieces in hand in variants. As for repetitions, the en passant square only
+// counts when the capture is legal. Equal positions always have equal
+// hashes, and different positions almost never do.
+func (g *Game) Hash() uint64 {
+	h := g.hash
+	if g.hasLegalEnPassant() {
+		h ^= zobristEnPassant[g.EnPassantTarget%8]
+	}
+	for c, n := range g.Checks {
+		h ^= zobristChecks[c][n]
+	}
+	for c := range g.Pockets {
+		for pt, n := range g.Pockets[c] {
+			h ^= zobristPockets[c][pt][n]
+		}
+	}
+	return h
+}
+
+// computeHash works out the hash from scratch, without the en passant
+// square, which Hash adds when it counts
+func (g *Game) computeHash() uint64 {
+	var key uint64
+	for sq, p := range g.Board {
+		key ^= zobristPiece(p, sq)
+	}
+	key ^= zobristCastlingRights(g.Castling)
+	if g.Turn == Black {
+		key ^= zobristBlack
+	}
+	return key
+}
+
+// Reached reports whether a position with the hash occurred on the line
+// leading to the current position, e.g. to find the games of a PGN
+// database that pass through a position
+func (g *Game) Reached(hash uint64) bool {
+	for _, h := range g.positionHashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
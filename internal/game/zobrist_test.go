@@ -0,0 +1,118 @@
+package game
+
+import "testing"
+
+// checkHash walks the move tree to depth and fails wherever the incremental
+// hash differs from one computed from scratch
+func checkHash(t *testing.T, g *Game, depth int) {
+	t.Helper()
+	if g.hash != g.computeHash() {
+		t.Fatalf("hash of %s is %x, computed %x", g.FEN(), g.hash, g.computeHash())
+	}
+	if depth == 0 {
+		return
+	}
+	for _, m := range g.GenerateLegalMoves() {
+		before := g.Hash()
+		g.MakeMove(m)
+		checkHash(t, g, depth-1)
+		g.UndoMove()
+		if g.Hash() != before {
+			t.Fatalf("undoing %s in %s did not restore the hash", g.UCI(m), g.FEN())
+		}
+	}
+}
+
+func TestHashMatchesFromScratch(t *testing.T) {
+	for _, pos := range perftPositions {
+		g := NewGame()
+		if err := g.LoadFEN(pos.fen); err != nil {
+			t.Fatal(err)
+		}
+		checkHash(t, g, 2)
+	}
+}
+
+func TestHashTranspositions(t *testing.T) {
+	play := func(moves ...string) *Game {
+		g := NewGame()
+		for _, san := range moves {
+			m, err := g.ParseSAN(san)
+			if err != nil {
+				t.Fatal(err)
+			}
+			g.MakeMove(m)
+		}
+		return g
+	}
+
+	if play("Nf3", "Nf6", "Nc3", "Nc6").Hash() != play("Nc3", "Nc6", "Nf3", "Nf6").Hash() {
+		t.Error("Move orders reaching the same position should give the same hash")
+	}
+	if !play("e4", "e5", "Nf3", "Nc6").Reached(play("e4", "e5").Hash()) || play("d4").Reached(play("e4").Hash()) {
+		t.Error("Reached should find exactly the positions on the game's line")
+	}
+	if NewGame().Hash() != play("Nf3", "Nf6", "Ng1", "Ng8").Hash() {
+		t.Error("A repeated position should give the same hash")
+	}
+	if NewGame().Hash() == play("Nf3", "Nf6", "Rg1", "Ng8", "Rh1", "Nf6", "Ng1", "Ng8").Hash() {
+		t.Error("Losing a castling right should change the hash")
+	}
+
+	fromFEN := func(fen string) uint64 {
+		g := NewGame()
+		if err := g.LoadFEN(fen); err != nil {
+			t.Fatal(err)
+		}
+		return g.Hash()
+	}
+	g := NewGame()
+	g.LoadFEN("4k3/8/8/8/3p4/8/4P3/4K3 w - - 0 1")
+	g.MakeMove(Move{From: 12, To: 28, Piece: Pawn}) // e2-e4 next to the d4 pawn
+	afterDoubleStep := g.Hash()
+	if afterDoubleStep != fromFEN("4k3/8/8/8/3pP3/8/8/4K3 b - e3 0 1") {
+		t.Error("Hash after a double step should match the position loaded with its en passant square")
+	}
+	if afterDoubleStep == fromFEN("4k3/8/8/8/3pP3/8/8/4K3 b - - 0 1") {
+		t.Error("A possible en passant capture should change the hash")
+	}
+}
+
+// Test that pockets and checks that differ by a few pieces or checks never
+// share a hash, which keys scaled by the count could
+func TestHashCounts(t *testing.T) {
+	g := NewVariantGame(Crazyhouse{})
+	seen := map[uint64][2][7]int{}
+	var pockets [2][7]int
+	var fill func(i int)
+	fill = func(i int) {
+		if i == 4 {
+			g.Pockets = pockets
+			if other, ok := seen[g.Hash()]; ok {
+				t.Fatalf("Pockets %v and %v have the same hash", pockets, other)
+			}
+			seen[g.Hash()] = pockets
+			return
+		}
+		// White and Black pawns and knights, up to 6 of each
+		c, pt := Color(i/2), Pawn+PieceType(i%2)
+		for n := 0; n <= 6; n++ {
+			pockets[c][pt] = n
+			fill(i + 1)
+		}
+		pockets[c][pt] = 0
+	}
+	fill(0)
+
+	g = NewVariantGame(ThreeCheck{})
+	hashes := map[uint64]bool{}
+	for w := 0; w <= ThreeCheckLimit; w++ {
+		for b := 0; b <= ThreeCheckLimit; b++ {
+			g.Checks = [2]int{w, b}
+			hashes[g.Hash()] = true
+		}
+	}
+	if len(hashes) != (ThreeCheckLimit+1)*(ThreeCheckLimit+1) {
+		t.Errorf("%d check counts share hashes", (ThreeCheckLimit+1)*(ThreeCheckLimit+1)-len(hashes))
+	}
+}
@@ -1,14 +1,17 @@
 package server
 
 import (
+	"crypto/rand"
 	"embed"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/fs"
 	"log"
-	"math/rand"
+	mathrand "math/rand"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -27,7 +30,13 @@ type Room struct {
 	Clients map[*websocket.Conn]game.Color // Map connection to player color
 	Mutex   sync.RWMutex
 	LastAct time.Time
-	Mode    string // "online" or "local"
+	Mode    string // "online", "local" or "analysis"
+
+	DrawOffer *game.Color // Side with a pending draw offer, if any
+
+	// Players maps the token handed to each player's connection to the side
+	// it controls. In local and analysis rooms one player has both sides.
+	Players map[string]game.Color
 }
 
 var (
@@ -53,16 +62,40 @@ type CreateRoomResponse struct {
 
 type GameStateResponse struct {
 	Board          []string `json:"board"`
+	FEN            string   `json:"fen"`
 	Turn           string   `json:"turn"`
 	InCheck        bool     `json:"inCheck"`
 	LegalMoves     []string `json:"legalMoves"`
+	Chess960       bool     `json:"chess960,omitempty"`
+	Variant        string   `json:"variant,omitempty"` // Name of the variant, empty for standard chess
+	Checks         []int    `json:"checks,omitempty"`  // Checks given by White and Black, in variants that count them
+	Pockets        []string `json:"pockets,omitempty"` // Pieces in hand of White and Black, e.g. ["NP", "q"], in variants with drops
 	GameOver       bool     `json:"gameOver"`
 	Winner         string   `json:"winner,omitempty"`
 	IsStalemate    bool     `json:"isStalemate"`
+	IsDraw         bool     `json:"isDraw"`
+	DrawReason     string   `json:"drawReason,omitempty"`
+	Result         string   `json:"result"`
+	Termination    string   `json:"termination,omitempty"`
+	HalfmoveClock  int      `json:"halfmoveClock"`
+	Repetitions    int      `json:"repetitions"`
+	FullmoveNumber int      `json:"fullmoveNumber"`
 	LastMove       string   `json:"lastMove,omitempty"`
 	CapturedPieces []string `json:"capturedPieces"`
 	SoundType      string   `json:"soundType,omitempty"`
 	PlayerCount    int      `json:"playerCount"`
+	DrawOffer      string   `json:"drawOffer,omitempty"`
+	ClaimableDraw  string   `json:"claimableDraw,omitempty"` // Rule under which the side to move may claim a draw, e.g. "threefold-repetition"
+
+	MoveTree    []MoveTreeNode `json:"moveTree,omitempty"` // Moves from the start position, main line first
+	CurrentPath []int          `json:"currentPath"`        // Child index at each step to the current position
+}
+
+// MoveTreeNode is one move of the game tree; Children[0] continues the main
+// line and further children are variations
+type MoveTreeNode struct {
+	SAN      string         `json:"san"`
+	Children []MoveTreeNode `json:"children,omitempty"`
 }
 
 type InitMessage struct {
@@ -70,6 +103,7 @@ type InitMessage struct {
 	Color  string            `json:"color"`
 	RoomID string            `json:"roomId"`
 	Mode   string            `json:"mode"`
+	Token  string            `json:"token,omitempty"` // Identifies the player to resign and draw requests; spectators get none
 	State  GameStateResponse `json:"state"`
 }
 
@@ -101,7 +135,7 @@ type PGNResponse struct {
 
 func StartServer() {
 	// Seed random for room codes
-	rand.Seed(time.Now().UnixNano())
+	mathrand.Seed(time.Now().UnixNano())
 
 	// Start cleanup goroutine
 	go cleanupRooms()
@@ -119,6 +153,10 @@ func StartServer() {
 	// --- NEW ENDPOINTS ---
 	http.HandleFunc("/api/play-ai", handlePlayAI)
 	http.HandleFunc("/api/export-pgn", handleExportPGN)
+	http.HandleFunc("/api/resign", handleResign)
+	http.HandleFunc("/api/offer-draw", handleOfferDraw)
+	http.HandleFunc("/api/claim-draw", handleClaimDraw)
+	http.HandleFunc("/api/navigate", handleNavigate)
 
 	log.Println("Server starting on http://localhost:8080")
 	log.Fatal(http.ListenAndServe(":8080", nil))
@@ -156,7 +194,7 @@ func handleConnections(w http.ResponseWriter, r *http.Request) {
 	var assignedColor game.Color
 
 	// Color Assignment Logic
-	if room.Mode == "local" {
+	if room.Mode == "local" || room.Mode == "analysis" {
 		assignedColor = game.White
 	} else {
 		// Online Multiplayer Logic
@@ -170,6 +208,11 @@ func handleConnections(w http.ResponseWriter, r *http.Request) {
 	}
 
 	room.Clients[ws] = assignedColor
+	token := ""
+	if room.Mode != "online" || clientCount < 2 {
+		token = generatePlayerToken()
+		room.Players[token] = assignedColor
+	}
 	room.Mutex.Unlock()
 
 	log.Printf("Client connected to Room: %s (%s) as %s", roomID, room.Mode, assignedColor)
@@ -181,6 +224,7 @@ func handleConnections(w http.ResponseWriter, r *http.Request) {
 		Color:  assignedColor.String(),
 		RoomID: roomID,
 		Mode:   room.Mode,
+		Token:  token,
 		State:  initState,
 	}
 	ws.WriteJSON(initMsg)
@@ -254,17 +298,54 @@ func handleCreateRoom(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Mode string `json:"mode"`
+		Mode     string `json:"mode"`
+		Variant  string `json:"variant"`  // "960" for Chess960 or a variant name such as "kingofthehill"
+		Position *int   `json:"position"` // Chess960 start position (0-959), random if omitted
+		FEN      string `json:"fen"`      // Optional starting position
+		PGN      string `json:"pgn"`      // Optional game to resume
 	}
 	_ = json.NewDecoder(r.Body).Decode(&req)
 
 	mode := req.Mode
-	if mode != "local" {
+	if mode != "local" && mode != "analysis" {
 		mode = "online"
 	}
 
-	roomID := generateRoomCode()
 	g := game.NewGame()
+	if req.Variant == "960" {
+		index := mathrand.Intn(960)
+		if req.Position != nil {
+			index = *req.Position
+		}
+		var err error
+		if g, err = game.NewChess960Game(index); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else if req.Variant != "" {
+		v, ok := game.VariantByName(req.Variant)
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown variant %q", req.Variant), http.StatusBadRequest)
+			return
+		}
+		g = game.NewVariantGame(v)
+	}
+	if req.PGN != "" {
+		imported, err := game.ParsePGN(req.PGN)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		g = imported
+	} else if req.FEN != "" {
+		if err := g.LoadFEN(req.FEN); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	roomID := generateRoomCode()
+	setDefaultTags(g, roomID, mode)
 
 	newRoom := &Room{
 		ID:      roomID,
@@ -272,6 +353,7 @@ func handleCreateRoom(w http.ResponseWriter, r *http.Request) {
 		Clients: make(map[*websocket.Conn]game.Color),
 		LastAct: time.Now(),
 		Mode:    mode,
+		Players: make(map[string]game.Color),
 	}
 
 	mu.Lock()
@@ -333,8 +415,14 @@ func handleMakeMove(w http.ResponseWriter, r *http.Request) {
 
 	room.Mutex.Lock()
 	g := room.Game
-	legalMoves := g.GenerateLegalMoves()
-	move, err := game.ParseMove(req.Move, legalMoves)
+	if g.Outcome().IsOver() {
+		room.Mutex.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(MoveResponse{Success: false, Error: "Game is over", State: getGameState(room, "")})
+		return
+	}
+
+	move, err := g.ParseMoveInput(req.Move)
 
 	if err != nil {
 		room.Mutex.Unlock()
@@ -348,7 +436,7 @@ func handleMakeMove(w http.ResponseWriter, r *http.Request) {
 	}
 
 	result := g.MakeMove(move)
-	room.LastAct = time.Now()
+	afterMove(room)
 	room.Mutex.Unlock()
 
 	soundType := determineSound(result)
@@ -387,6 +475,12 @@ func handlePlayAI(w http.ResponseWriter, r *http.Request) {
 
 	room.Mutex.Lock()
 	g := room.Game
+	if g.Outcome().IsOver() {
+		room.Mutex.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(MoveResponse{Success: false, Error: "Game is over"})
+		return
+	}
 
 	// AI Logic
 	bestMove, err := g.GetBestMove(3) // Depth 3
@@ -398,7 +492,7 @@ func handlePlayAI(w http.ResponseWriter, r *http.Request) {
 	}
 
 	result := g.MakeMove(bestMove)
-	room.LastAct = time.Now()
+	afterMove(room)
 	room.Mutex.Unlock()
 
 	soundType := determineSound(result)
@@ -411,7 +505,7 @@ func handlePlayAI(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handleExportPGN returns the PGN string
+// handleExportPGN returns the game in PGN export format
 func handleExportPGN(w http.ResponseWriter, r *http.Request) {
 	roomID := r.URL.Query().Get("roomId")
 
@@ -428,6 +522,14 @@ func handleExportPGN(w http.ResponseWriter, r *http.Request) {
 	pgn := room.Game.GeneratePGN()
 	room.Mutex.RUnlock()
 
+	// ?download=1 returns the PGN as a file instead of JSON
+	if r.URL.Query().Get("download") != "" {
+		w.Header().Set("Content-Type", "application/x-chess-pgn")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"chess-%s.pgn\"", roomID))
+		io.WriteString(w, pgn)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(PGNResponse{PGN: pgn})
 }
@@ -457,7 +559,13 @@ func handleUndoMove(w http.ResponseWriter, r *http.Request) {
 	}
 
 	room.Mutex.Lock()
-	room.Game.UndoMove()
+	if room.Mode == "analysis" {
+		// Keep the move so the line can be revisited
+		room.Game.Back()
+	} else {
+		room.Game.UndoMove()
+	}
+	room.DrawOffer = nil
 	room.LastAct = time.Now()
 	room.Mutex.Unlock()
 
@@ -470,6 +578,196 @@ func handleUndoMove(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleResign ends the game with a win for the other side
+func handleResign(w http.ResponseWriter, r *http.Request) {
+	room, color, ok := decodePlayerRequest(w, r)
+	if !ok {
+		return
+	}
+
+	room.Mutex.Lock()
+	err := room.Game.Resign(color)
+	room.LastAct = time.Now()
+	room.Mutex.Unlock()
+
+	respondWithOutcome(w, room, err)
+}
+
+// handleOfferDraw records a draw offer; the game is drawn once both sides have offered
+func handleOfferDraw(w http.ResponseWriter, r *http.Request) {
+	room, color, ok := decodePlayerRequest(w, r)
+	if !ok {
+		return
+	}
+
+	room.Mutex.Lock()
+	var err error
+	if room.Game.Outcome().IsOver() {
+		err = fmt.Errorf("game is already over")
+	} else if room.DrawOffer != nil && *room.DrawOffer != color {
+		err = room.Game.AgreeDraw()
+		room.DrawOffer = nil
+	} else {
+		room.DrawOffer = &color
+	}
+	room.LastAct = time.Now()
+	room.Mutex.Unlock()
+
+	respondWithOutcome(w, room, err)
+}
+
+// handleClaimDraw ends the game as a draw by threefold repetition or the
+// fifty-move rule, which only the side to move may claim
+func handleClaimDraw(w http.ResponseWriter, r *http.Request) {
+	room, color, ok := decodePlayerRequest(w, r)
+	if !ok {
+		return
+	}
+
+	room.Mutex.Lock()
+	var err error
+	if color != room.Game.Turn {
+		err = fmt.Errorf("only %s, to move, can claim a draw", room.Game.Turn)
+	} else {
+		err = room.Game.ClaimDraw()
+	}
+	room.LastAct = time.Now()
+	room.Mutex.Unlock()
+
+	respondWithOutcome(w, room, err)
+}
+
+// handleNavigate moves through the game tree of an analysis room. Actions are
+// "start", "back", "forward", "end", "goto" (to path) and "promote" (the
+// variation at path, or the current move, becomes the main line).
+func handleNavigate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		RoomID string `json:"roomId"`
+		Action string `json:"action"`
+		Path   []int  `json:"path"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	mu.RLock()
+	room, exists := rooms[req.RoomID]
+	mu.RUnlock()
+
+	if !exists {
+		http.Error(w, "Room not found", http.StatusNotFound)
+		return
+	}
+
+	room.Mutex.Lock()
+	g := room.Game
+	var err error
+	switch {
+	case room.Mode != "analysis":
+		err = fmt.Errorf("navigation is only available in analysis mode")
+	case req.Action == "start":
+		err = g.GoTo(g.Root)
+	case req.Action == "back":
+		g.Back()
+	case req.Action == "forward":
+		g.Forward()
+	case req.Action == "end":
+		for g.Forward() {
+		}
+	case req.Action == "goto":
+		err = g.GoTo(g.NodeAt(req.Path))
+	case req.Action == "promote":
+		node := g.CurrentNode()
+		if req.Path != nil {
+			node = g.NodeAt(req.Path)
+		}
+		err = g.PromoteVariation(node)
+	default:
+		err = fmt.Errorf("unknown action %q", req.Action)
+	}
+	room.LastAct = time.Now()
+	room.Mutex.Unlock()
+
+	respondWithOutcome(w, room, err)
+}
+
+// decodePlayerRequest reads a {roomId, token, color} body, looks up the room
+// and works out which side the request acts for. In online rooms that is the
+// side the token was issued to, and a different color in the body is
+// rejected. In local and analysis rooms the player controls both sides, so
+// the body color is used, defaulting to the side to move.
+func decodePlayerRequest(w http.ResponseWriter, r *http.Request) (*Room, game.Color, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, game.White, false
+	}
+
+	var req struct {
+		RoomID string `json:"roomId"`
+		Token  string `json:"token"`
+		Color  string `json:"color"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return nil, game.White, false
+	}
+
+	mu.RLock()
+	room, exists := rooms[req.RoomID]
+	mu.RUnlock()
+
+	if !exists {
+		http.Error(w, "Room not found", http.StatusNotFound)
+		return nil, game.White, false
+	}
+
+	room.Mutex.RLock()
+	color, isPlayer := room.Players[req.Token]
+	if room.Mode != "online" {
+		color = room.Game.Turn
+		if req.Color != "" {
+			color = game.White
+			if req.Color == game.Black.String() {
+				color = game.Black
+			}
+		}
+	}
+	room.Mutex.RUnlock()
+
+	if !isPlayer {
+		http.Error(w, "Not a player in this room", http.StatusForbidden)
+		return nil, game.White, false
+	}
+	if req.Color != "" && req.Color != color.String() {
+		http.Error(w, fmt.Sprintf("Cannot act for %s", req.Color), http.StatusForbidden)
+		return nil, game.White, false
+	}
+	return room, color, true
+}
+
+// respondWithOutcome broadcasts the room state and reports whether the action succeeded
+func respondWithOutcome(w http.ResponseWriter, room *Room, err error) {
+	response := MoveResponse{Success: err == nil}
+	if err != nil {
+		response.Error = err.Error()
+	} else {
+		go broadcastState(room, "")
+	}
+
+	room.Mutex.RLock()
+	response.State = getGameState(room, "")
+	room.Mutex.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 func handleGetSounds(w http.ResponseWriter, r *http.Request) {
 	sounds := SoundData{
 		Move:      "/move.mp3",
@@ -506,6 +804,13 @@ func determineSound(result game.MoveResult) string {
 	return "move"
 }
 
+// afterMove updates room bookkeeping once a move has been played. Must be
+// called with the room lock held.
+func afterMove(room *Room) {
+	room.LastAct = time.Now()
+	room.DrawOffer = nil
+}
+
 func getGameState(room *Room, soundType string) GameStateResponse {
 	g := room.Game
 	board := make([]string, 64)
@@ -516,23 +821,17 @@ func getGameState(room *Room, soundType string) GameStateResponse {
 	legalMoves := g.GenerateLegalMoves()
 	legalMovesStr := make([]string, len(legalMoves))
 	for i, m := range legalMoves {
-		legalMovesStr[i] = fmt.Sprintf("%s%s", game.IndexToCoord(m.From), game.IndexToCoord(m.To))
+		legalMovesStr[i] = g.UCI(m)
 	}
 
-	gameOver := len(legalMoves) == 0
+	outcome := g.Outcome()
 	winner := ""
-	isStalemate := false
-
-	if gameOver {
-		if g.Board.InCheck(g.Turn) {
-			if g.Turn == game.White {
-				winner = "Black"
-			} else {
-				winner = "White"
-			}
-		} else {
-			isStalemate = true
-		}
+	if c, ok := outcome.Winner(); ok {
+		winner = c.String()
+	}
+	drawReason := ""
+	if outcome.Result == game.Draw {
+		drawReason = outcome.Termination.String()
 	}
 
 	lastMove := ""
@@ -543,55 +842,138 @@ func getGameState(room *Room, soundType string) GameStateResponse {
 
 	capturedPieces := getCapturedPieces(g)
 
+	drawOffer := ""
+	if room.DrawOffer != nil {
+		drawOffer = room.DrawOffer.String()
+	}
+	claimableDraw := ""
+	if !outcome.IsOver() {
+		if reason := g.ClaimableDraw(); reason != game.TerminationNone {
+			claimableDraw = reason.String()
+		}
+	}
+
+	variant := ""
+	var checks []int
+	var pockets []string
+	if g.Variant != nil {
+		variant = g.Variant.Name()
+		if g.Variant.CountsChecks() {
+			checks = g.Checks[:]
+		}
+		if g.Variant.HasPockets() {
+			pockets = getPockets(g)
+		}
+	}
+
 	return GameStateResponse{
 		Board:          board,
+		FEN:            g.FEN(),
 		Turn:           g.Turn.String(),
-		InCheck:        g.Board.InCheck(g.Turn),
+		InCheck:        g.InCheck(),
 		LegalMoves:     legalMovesStr,
-		GameOver:       gameOver,
+		Chess960:       g.Chess960,
+		Variant:        variant,
+		Checks:         checks,
+		Pockets:        pockets,
+		GameOver:       outcome.IsOver(),
 		Winner:         winner,
-		IsStalemate:    isStalemate,
+		IsStalemate:    outcome.Termination == game.TerminationStalemate,
+		IsDraw:         outcome.Result == game.Draw,
+		DrawReason:     drawReason,
+		Result:         outcome.Result.String(),
+		Termination:    outcome.Termination.String(),
+		HalfmoveClock:  g.HalfmoveClock,
+		Repetitions:    g.RepetitionCount(),
+		FullmoveNumber: g.FullmoveNumber,
 		LastMove:       lastMove,
 		CapturedPieces: capturedPieces,
 		SoundType:      soundType,
 		PlayerCount:    len(room.Clients),
+		DrawOffer:      drawOffer,
+		ClaimableDraw:  claimableDraw,
+		MoveTree:       moveTreeNodes(g.Root),
+		CurrentPath:    g.CurrentNode().Path(),
+	}
+}
+
+// moveTreeNodes converts the continuations of n for the client
+func moveTreeNodes(n *game.MoveNode) []MoveTreeNode {
+	nodes := make([]MoveTreeNode, len(n.Children))
+	for i, c := range n.Children {
+		nodes[i] = MoveTreeNode{SAN: c.SAN, Children: moveTreeNodes(c)}
 	}
+	return nodes
 }
 
+// getCapturedPieces lists the pieces taken along the current line, each as
+// its color ("w" or "b") followed by its FEN letter
 func getCapturedPieces(g *game.Game) []string {
 	captured := []string{}
-	whitePieces := map[game.PieceType]int{game.Pawn: 8, game.Knight: 2, game.Bishop: 2, game.Rook: 2, game.Queen: 1, game.King: 1}
-	blackPieces := map[game.PieceType]int{game.Pawn: 8, game.Knight: 2, game.Bishop: 2, game.Rook: 2, game.Queen: 1, game.King: 1}
-
-	for i := 0; i < 64; i++ {
-		piece := g.Board[i]
-		if piece.Type != game.Empty {
-			if piece.Color == game.White {
-				whitePieces[piece.Type]--
-			} else {
-				blackPieces[piece.Type]--
-			}
+	for _, p := range g.CapturedPieces() {
+		prefix := "w"
+		if p.Color == game.Black {
+			prefix = "b"
 		}
+		captured = append(captured, prefix+p.String())
 	}
+	return captured
+}
 
-	for pType, count := range whitePieces {
-		for i := 0; i < count; i++ {
-			captured = append(captured, "w"+string(game.Piece{Type: pType, Color: game.White}.String()))
+// getPockets lists the pieces in each side's pocket as FEN letters
+func getPockets(g *game.Game) []string {
+	pockets := make([]string, 2)
+	for c, pocket := range g.Pockets {
+		for pt := game.Queen; pt >= game.Pawn; pt-- {
+			piece := game.Piece{Type: pt, Color: game.Color(c)}.String()
+			pockets[c] += strings.Repeat(piece, pocket[pt])
 		}
 	}
-	for pType, count := range blackPieces {
-		for i := 0; i < count; i++ {
-			captured = append(captured, "b"+string(game.Piece{Type: pType, Color: game.Black}.String()))
+	return pockets
+}
+
+// setDefaultTags fills in the PGN Seven Tag Roster fields the server knows
+// about, keeping any tags already present (e.g. from an imported game)
+func setDefaultTags(g *game.Game, roomID, mode string) {
+	if g.Tags == nil {
+		g.Tags = map[string]string{}
+	}
+
+	defaults := map[string]string{
+		"Event": "Casual game",
+		"Site":  "Chess-app room " + roomID,
+		"Date":  time.Now().Format("2006.01.02"),
+		"White": "White",
+		"Black": "Black",
+	}
+	switch mode {
+	case "local":
+		defaults["Event"] = "Pass and play"
+	case "analysis":
+		defaults["Event"] = "Analysis"
+	}
+
+	for name, value := range defaults {
+		if _, ok := g.Tags[name]; !ok {
+			g.Tags[name] = value
 		}
 	}
-	return captured
+}
+
+// generatePlayerToken returns a random token that is hard to guess
+func generatePlayerToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatalf("Failed to generate player token: %v", err)
+	}
+	return hex.EncodeToString(b)
 }
 
 func generateRoomCode() string {
 	const letters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
 	b := make([]byte, 4)
 	for i := range b {
-		b[i] = letters[rand.Intn(len(letters))]
+		b[i] = letters[mathrand.Intn(len(letters))]
 	}
 	return string(b)
 }
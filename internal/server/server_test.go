@@ -0,0 +1,118 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Waleed-Ahmad-dev/Chess-app/internal/game"
+	"github.com/gorilla/websocket"
+)
+
+// newTestRoom registers a room whose players hold the given tokens
+func newTestRoom(t *testing.T, mode string, players map[string]game.Color) *Room {
+	t.Helper()
+	room := &Room{
+		ID:      generateRoomCode(),
+		Game:    game.NewGame(),
+		Clients: make(map[*websocket.Conn]game.Color),
+		LastAct: time.Now(),
+		Mode:    mode,
+		Players: players,
+	}
+	mu.Lock()
+	rooms[room.ID] = room
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		delete(rooms, room.ID)
+		mu.Unlock()
+	})
+	return room
+}
+
+func post(handler http.HandlerFunc, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	return w
+}
+
+// Test that a player cannot resign or offer a draw for the opponent
+func TestPlayerRequestsUseTheTokenColor(t *testing.T) {
+	room := newTestRoom(t, "online", map[string]game.Color{"white-token": game.White, "black-token": game.Black})
+
+	if w := post(handleResign, `{"roomId":"`+room.ID+`","token":"white-token","color":"Black"}`); w.Code != http.StatusForbidden {
+		t.Errorf("Resigning for the opponent: status %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := post(handleOfferDraw, `{"roomId":"`+room.ID+`","color":"Black"}`); w.Code != http.StatusForbidden {
+		t.Errorf("Offering a draw without a token: status %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if room.Game.Outcome().IsOver() || room.DrawOffer != nil {
+		t.Fatal("A forged request changed the game")
+	}
+
+	if w := post(handleResign, `{"roomId":"`+room.ID+`","token":"black-token"}`); w.Code != http.StatusOK {
+		t.Fatalf("Resigning: status %d", w.Code)
+	}
+	if o := room.Game.Outcome(); o.Result != game.WhiteWins {
+		t.Errorf("Outcome = %v, want White to win by resignation", o)
+	}
+}
+
+// Test that a local draw needs an offer and an acceptance from the other side
+func TestLocalDrawNeedsBothSides(t *testing.T) {
+	room := newTestRoom(t, "local", map[string]game.Color{"token": game.White})
+
+	post(handleOfferDraw, `{"roomId":"`+room.ID+`","token":"token","color":"White"}`)
+	post(handleOfferDraw, `{"roomId":"`+room.ID+`","token":"token","color":"White"}`)
+	if room.Game.Outcome().IsOver() {
+		t.Fatal("One side's offer drew the game")
+	}
+	post(handleOfferDraw, `{"roomId":"`+room.ID+`","token":"token","color":"Black"}`)
+	if o := room.Game.Outcome(); o.Result != game.Draw {
+		t.Errorf("Outcome = %v, want an agreed draw", o)
+	}
+}
+
+// Test that threefold repetition is reported but only drawn once claimed
+func TestClaimDraw(t *testing.T) {
+	room := newTestRoom(t, "online", map[string]game.Color{"white-token": game.White, "black-token": game.Black})
+	for i := 0; i < 2; i++ {
+		for _, move := range []string{"g1f3", "g8f6", "f3g1", "f6g8"} {
+			post(handleMakeMove, `{"roomId":"`+room.ID+`","move":"`+move+`"}`)
+		}
+	}
+
+	state := getGameState(room, "")
+	if state.GameOver || state.ClaimableDraw != "threefold-repetition" {
+		t.Fatalf("GameOver = %v, ClaimableDraw = %q; want a claimable threefold repetition", state.GameOver, state.ClaimableDraw)
+	}
+	post(handleClaimDraw, `{"roomId":"`+room.ID+`","token":"black-token"}`)
+	if room.Game.Outcome().IsOver() {
+		t.Fatal("The side not to move claimed a draw")
+	}
+	post(handleClaimDraw, `{"roomId":"`+room.ID+`","token":"white-token"}`)
+	if o := room.Game.Outcome(); o.Termination != game.TerminationThreefoldRepetition {
+		t.Errorf("Outcome = %v, want a draw by threefold repetition", o)
+	}
+}
+
+// Test that captured pieces come from the moves played, not from a count
+// against the standard set of pieces
+func TestCapturedPiecesInVariants(t *testing.T) {
+	room := newTestRoom(t, "local", nil)
+	room.Game = game.NewVariantGame(game.Horde{})
+	if got := getGameState(room, "").CapturedPieces; len(got) != 0 {
+		t.Errorf("Expected no captured pieces at the start of Horde, got %v", got)
+	}
+
+	room.Game = game.NewGame()
+	for _, move := range []string{"e2e4", "d7d5", "e4d5"} {
+		post(handleMakeMove, `{"roomId":"`+room.ID+`","move":"`+move+`"}`)
+	}
+	if got := getGameState(room, "").CapturedPieces; len(got) != 1 || got[0] != "bp" {
+		t.Errorf("Expected the black pawn taken on d5, got %v", got)
+	}
+}